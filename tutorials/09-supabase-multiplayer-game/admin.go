@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"crewmate-crisis/api"
+)
+
+// requireAdmin protects the teacher dashboard with HTTP basic auth. Any
+// username is accepted; the password must match ADMIN_PASSWORD. The
+// dashboard is disabled when no password is configured.
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.AdminPassword == "" {
+			httpError(w, r, "Admin dashboard is disabled. Set ADMIN_PASSWORD to enable it.", http.StatusForbidden)
+			return
+		}
+
+		_, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(s.config.AdminPassword)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Crewmate Crisis Admin"`)
+			httpError(w, r, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) adminPage(w http.ResponseWriter, r *http.Request) {
+	http.ServeFile(w, r, "./static/admin.html")
+}
+
+func (s *Server) adminListRooms(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT gr.id, gr.room_code, gr.status, gr.meeting_started_at IS NOT NULL,
+			COALESCE(p.username, ''), gr.current_game_id, gr.created_at,
+			(SELECT COUNT(*) FROM room_players rp WHERE rp.room_id = gr.id),
+			(SELECT COUNT(*) FROM room_players rp WHERE rp.room_id = gr.id AND rp.is_alive = true),
+			(SELECT COUNT(*) FROM messages m WHERE m.room_id = gr.id)
+		FROM game_rooms gr
+		LEFT JOIN players p ON gr.host_id = p.id
+		ORDER BY gr.created_at DESC`)
+
+	if err != nil {
+		serverError(w, r, "Failed to list rooms", err)
+		return
+	}
+	defer rows.Close()
+
+	rooms := make([]api.RoomSummary, 0)
+	for rows.Next() {
+		var room api.RoomSummary
+		var status string
+		var inMeeting bool
+		var gameID sql.NullString
+		err := rows.Scan(&room.ID, &room.RoomCode, &status, &inMeeting, &room.HostUsername, &gameID,
+			&room.CreatedAt, &room.PlayerCount, &room.AliveCount, &room.MessageCount)
+		if err != nil {
+			continue
+		}
+		room.Phase = roomPhase(status, inMeeting)
+		room.GameID = gameID.String
+		rooms = append(rooms, room)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rooms)
+}
+
+func (s *Server) adminEndGame(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	roomCode := vars["code"]
+
+	var roomID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id FROM game_rooms WHERE room_code = $1`,
+		roomCode).Scan(&roomID)
+
+	if err == sql.ErrNoRows {
+		apiError(w, r, api.CodeRoomNotFound, "Room not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		serverError(w, r, "Failed to get room", err)
+		return
+	}
+
+	// End the running game without a winner; lobbies are simply closed
+	game, err := s.currentGame(ctx, roomID)
+	if err != nil && err != sql.ErrNoRows {
+		serverError(w, r, "Failed to get game", err)
+		return
+	}
+
+	if game != nil {
+		if err := s.endGame(ctx, game, winnerNobody); err != nil {
+			serverError(w, r, "Failed to end game", err)
+			return
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE game_rooms SET status = $1 WHERE id = $2`,
+		"finished", roomID)
+
+	if err != nil {
+		serverError(w, r, "Failed to close room", err)
+		return
+	}
+
+	logger(ctx).Info("admin ended room")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.RoomEnded{
+		Status:   "game_ended",
+		RoomCode: roomCode,
+	})
+}
+
+func (s *Server) adminKickPlayer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	roomCode := vars["code"]
+	playerID := vars["playerID"]
+	if !isUUID(playerID) {
+		httpError(w, r, "Invalid player ID", http.StatusBadRequest)
+		return
+	}
+
+	var roomID string
+	var gameID sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, current_game_id FROM game_rooms WHERE room_code = $1`,
+		roomCode).Scan(&roomID, &gameID)
+
+	if err == sql.ErrNoRows {
+		apiError(w, r, api.CodeRoomNotFound, "Room not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		serverError(w, r, "Failed to get room", err)
+		return
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM room_players WHERE room_id = $1 AND player_id = $2`,
+		roomID, playerID)
+
+	if err != nil {
+		serverError(w, r, "Failed to kick player", err)
+		return
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		httpError(w, r, "Player not in room", http.StatusNotFound)
+		return
+	}
+
+	s.recordEvent(ctx, roomID, gameID.String, eventPlayerKicked, "", playerID, nil)
+	logger(ctx).Info("admin kicked player")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.PlayerKicked{
+		Status:   "player_kicked",
+		PlayerID: playerID,
+	})
+}
+
+func (s *Server) adminWipeChat(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	roomCode := vars["code"]
+
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM messages
+		WHERE room_id = (SELECT id FROM game_rooms WHERE room_code = $1)`,
+		roomCode)
+
+	if err != nil {
+		serverError(w, r, "Failed to wipe chat", err)
+		return
+	}
+
+	deleted, _ := result.RowsAffected()
+	logger(ctx).Info("admin wiped chat", "messages", deleted)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.ChatWiped{
+		Status:  "chat_wiped",
+		Deleted: deleted,
+	})
+}
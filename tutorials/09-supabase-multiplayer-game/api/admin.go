@@ -0,0 +1,95 @@
+package api
+
+import "time"
+
+// RoomSummary is one row in the teacher dashboard's room list
+type RoomSummary struct {
+	ID           string    `json:"id"`
+	RoomCode     string    `json:"room_code"`
+	Phase        string    `json:"phase"`
+	HostUsername string    `json:"host_username"`
+	PlayerCount  int       `json:"player_count"`
+	AliveCount   int       `json:"alive_count"`
+	MessageCount int       `json:"message_count"`
+	GameID       string    `json:"game_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RoomEnded is returned when the teacher closes a room
+type RoomEnded struct {
+	Status   string `json:"status"`
+	RoomCode string `json:"room_code"`
+}
+
+// PlayerKicked is returned when the teacher removes a player
+type PlayerKicked struct {
+	Status   string `json:"status"`
+	PlayerID string `json:"player_id"`
+}
+
+// ChatWiped is returned when the teacher deletes a room's chat
+type ChatWiped struct {
+	Status  string `json:"status"`
+	Deleted int64  `json:"deleted"`
+}
+
+// ModerationEntry is a chat message the moderator acted on
+type ModerationEntry struct {
+	ID        int64     `json:"id"`
+	RoomCode  string    `json:"room_code"`
+	PlayerID  string    `json:"player_id"`
+	Username  string    `json:"username"`
+	Action    string    `json:"action"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ClassSession groups the rooms played during one class. While a session
+// is active, new rooms belong to it and only names on its roster can
+// create or join rooms.
+type ClassSession struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Roster    []string   `json:"roster"`
+	CreatedAt time.Time  `json:"created_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// CreateSessionRequest starts a class session, ending any active one
+type CreateSessionRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Roster []string `json:"roster" validate:"required,max=500,dive,max=20"` // names, as in CreateRoomRequest
+}
+
+// SessionSummary is one row in the list of class sessions
+type SessionSummary struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Active     bool       `json:"active"`
+	RoomCount  int        `json:"room_count"`
+	RosterSize int        `json:"roster_size"`
+	CreatedAt  time.Time  `json:"created_at"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"`
+}
+
+// SessionEnded is returned when the teacher ends a class session
+type SessionEnded struct {
+	Status    string `json:"status"`
+	SessionID string `json:"session_id"`
+}
+
+// SessionReport is everything played during a class session
+type SessionReport struct {
+	Session ClassSession        `json:"session"`
+	Rooms   []SessionRoomReport `json:"rooms"`
+}
+
+// SessionRoomReport summarizes one room played during a session
+type SessionRoomReport struct {
+	RoomCode    string        `json:"room_code"`
+	Status      string        `json:"status"`
+	GamesPlayed int           `json:"games_played"`
+	Players     []string      `json:"players"`
+	Scores      []PlayerScore `json:"scores"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
@@ -0,0 +1,270 @@
+// Package api defines the requests and responses of the game server's
+// /api endpoints. The server encodes these types, describes them in the
+// OpenAPI document at /api/openapi.json, and checks requests with
+// Validate; tools written in Go can use them to talk to the server.
+package api
+
+import "time"
+
+// DefaultAvatarColor is used when a player doesn't pick a color
+const DefaultAvatarColor = "blue"
+
+// MaxUsernameLength matches the name field on the welcome screen
+const MaxUsernameLength = 20
+
+// Room statuses stored in game_rooms.status
+const (
+	StatusWaiting  = "waiting"
+	StatusPlaying  = "playing"
+	StatusFinished = "finished"
+)
+
+// CreateRoomRequest creates a room with the caller as its host
+type CreateRoomRequest struct {
+	Username    string `json:"username" validate:"required,max=20"`
+	AvatarColor string `json:"avatar_color,omitempty" validate:"oneof=red blue green yellow purple orange pink cyan"`
+}
+
+// JoinRoomRequest adds the caller to a room
+type JoinRoomRequest struct {
+	RoomCode    string `json:"room_code" validate:"required,roomcode"`
+	Username    string `json:"username" validate:"required,max=20"`
+	AvatarColor string `json:"avatar_color,omitempty" validate:"oneof=red blue green yellow purple orange pink cyan"`
+}
+
+// JoinedRoom is returned when a player creates or joins a room. PlayerID
+// identifies the player in later requests.
+type JoinedRoom struct {
+	RoomID      string `json:"room_id"`
+	RoomCode    string `json:"room_code"`
+	PlayerID    string `json:"player_id"`
+	PlayerToken string `json:"player_token"` // secret; send as X-Player-Token to read private chat
+	Host        bool   `json:"host"`
+}
+
+// GameRoom is a room and everyone in it
+type GameRoom struct {
+	ID         string       `json:"id"`
+	RoomCode   string       `json:"room_code"`
+	HostID     string       `json:"host_id"`
+	Status     string       `json:"status"`
+	ImpostorID string       `json:"impostor_id,omitempty"` // only for the impostor, or once the game is finished
+	Players    []Player     `json:"players"`
+	Settings   RoomSettings `json:"settings"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// Player is a player's state in a room
+type Player struct {
+	ID             string `json:"id"`
+	Username       string `json:"username"`
+	AvatarColor    string `json:"avatar_color"`
+	IsAlive        bool   `json:"is_alive"`
+	TasksCompleted int    `json:"tasks_completed"`
+}
+
+// RoomSettings are the chat rules the host can change in the lobby
+type RoomSettings struct {
+	ImpostorChat    bool `json:"impostor_chat"`
+	ChatPresetsOnly bool `json:"chat_presets_only"`
+}
+
+// RoomSettingsRequest changes a room's settings. Only the host may send
+// it; settings left out are unchanged.
+type RoomSettingsRequest struct {
+	PlayerID        string `json:"player_id" validate:"required,uuid"`
+	ImpostorChat    *bool  `json:"impostor_chat,omitempty"`
+	ChatPresetsOnly *bool  `json:"chat_presets_only,omitempty"`
+}
+
+// GameStarted is returned when the host starts a game
+type GameStarted struct {
+	Status     string `json:"status"`
+	GameID     string `json:"game_id"`
+	ImpostorID string `json:"impostor_id,omitempty"` // only when the host is the impostor
+	Message    string `json:"message"`
+}
+
+// VoteRequest casts a vote in the meeting that's open. An empty suspect
+// is a skip vote. The room comes from the URL and the round from the
+// server; RoomID and Round, if sent, must match them.
+type VoteRequest struct {
+	RoomID    string `json:"room_id,omitempty" validate:"uuid"`
+	VoterID   string `json:"voter_id" validate:"required,uuid"`
+	SuspectID string `json:"suspect_id,omitempty" validate:"uuid"`
+	Round     int    `json:"round,omitempty"`
+}
+
+// VoteResult reports whether voting has finished. Once everyone alive
+// has voted, EjectedID is the player voted out (empty when nobody was)
+// and Winner is set if that ended the game.
+type VoteResult struct {
+	Status    string `json:"status"` // vote_recorded or voting_complete
+	EjectedID string `json:"ejected_id,omitempty"`
+	Winner    string `json:"winner,omitempty"`
+}
+
+// TaskRequest reports that a player finished a task. Task IDs are the
+// ones in the game's task list; each counts once per game.
+type TaskRequest struct {
+	PlayerID string `json:"player_id" validate:"required,uuid"`
+	TaskID   string `json:"task_id" validate:"required,oneof=task1 task2 task3 task4 task5"`
+}
+
+// TaskResult has the winner when the task completed the crew's work
+type TaskResult struct {
+	Status string `json:"status"`
+	Winner string `json:"winner,omitempty"`
+}
+
+// EmergencyRequest calls an emergency meeting
+type EmergencyRequest struct {
+	PlayerID string `json:"player_id" validate:"required,uuid"`
+}
+
+// MeetingCalled is returned when an emergency meeting starts
+type MeetingCalled struct {
+	Status  string `json:"status"`
+	Room    string `json:"room"`
+	Message string `json:"message"`
+}
+
+// MessageRequest sends a chat message: either free text in Content, or a
+// quick-chat preset and its params. Channel defaults to the one the
+// player can use in the current phase.
+type MessageRequest struct {
+	PlayerID string            `json:"player_id" validate:"required,uuid"`
+	Content  string            `json:"content,omitempty"`
+	Channel  string            `json:"channel,omitempty" validate:"oneof=public ghost impostor"`
+	PresetID string            `json:"preset_id,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+}
+
+func (r *MessageRequest) check() error {
+	if r.PresetID == "" && r.Content == "" {
+		return &FieldError{Field: "content", Message: "content or preset_id is required"}
+	}
+	return nil
+}
+
+// MessageSent is the message as saved, after moderation
+type MessageSent struct {
+	Status    string `json:"status"`
+	MessageID string `json:"message_id"`
+	Content   string `json:"content"`
+	Channel   string `json:"channel"`
+	PresetID  string `json:"preset_id"`
+}
+
+// ChatMessage is a message in a room's chat history
+type ChatMessage struct {
+	ID          string    `json:"id"`
+	PlayerID    string    `json:"player_id"`
+	Content     string    `json:"content"`
+	Channel     string    `json:"channel"`
+	PresetID    string    `json:"preset_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Username    string    `json:"username"`
+	AvatarColor string    `json:"avatar_color"`
+}
+
+// QuickChatPreset is a ready-made phrase younger players can send
+// without typing. {player} and {location} are filled in from the
+// message's params.
+type QuickChatPreset struct {
+	ID     string   `json:"id"`
+	Text   string   `json:"text"`
+	Params []string `json:"params"`
+}
+
+// QuickChatCatalog lists the presets and the locations they can name
+type QuickChatCatalog struct {
+	Presets   []QuickChatPreset `json:"presets"`
+	Locations []string          `json:"locations"`
+}
+
+// PlayerScore is a player's running total across every game in a room
+type PlayerScore struct {
+	PlayerID       string `json:"player_id"`
+	Username       string `json:"username"`
+	AvatarColor    string `json:"avatar_color"`
+	Points         int    `json:"points"`
+	GamesPlayed    int    `json:"games_played"`
+	CrewmateWins   int    `json:"crewmate_wins"`
+	ImpostorWins   int    `json:"impostor_wins"`
+	CorrectVotes   int    `json:"correct_votes"`
+	TasksCompleted int    `json:"tasks_completed"`
+}
+
+// Scoreboard is a room's scores, highest first
+type Scoreboard struct {
+	RoomCode    string        `json:"room_code"`
+	GamesPlayed int           `json:"games_played"`
+	Scores      []PlayerScore `json:"scores"`
+}
+
+// GameEvent is a single entry in a game's replay timeline, also sent
+// live on a room's event stream
+type GameEvent struct {
+	Seq       int64                  `json:"seq"`
+	Type      string                 `json:"type"`
+	PlayerID  string                 `json:"player_id,omitempty"`
+	Username  string                 `json:"username,omitempty"`
+	TargetID  string                 `json:"target_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Replay is a finished game's event log
+type Replay struct {
+	GameID    string      `json:"game_id"`
+	RoomCode  string      `json:"room_code"`
+	Winner    string      `json:"winner"`
+	StartedAt time.Time   `json:"started_at"`
+	EndedAt   time.Time   `json:"ended_at"`
+	Events    []GameEvent `json:"events"`
+}
+
+// Transcript is a finished game's chat, meetings and votes for classroom review
+type Transcript struct {
+	RoomCode  string             `json:"room_code"`
+	GameID    string             `json:"game_id"`
+	Winner    string             `json:"winner"`
+	StartedAt time.Time          `json:"started_at"`
+	EndedAt   time.Time          `json:"ended_at"`
+	Players   []TranscriptPlayer `json:"players"`
+	Timeline  []TranscriptEntry  `json:"timeline"`
+	Rounds    []VoteRound        `json:"rounds"`
+}
+
+// TranscriptPlayer is a player and the role they had in the game
+type TranscriptPlayer struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// TranscriptEntry is a chat message or a meeting being called
+type TranscriptEntry struct {
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"` // chat, meeting
+	Channel  string    `json:"channel,omitempty"`
+	PlayerID string    `json:"player_id,omitempty"`
+	Username string    `json:"username,omitempty"`
+	Content  string    `json:"content,omitempty"`
+}
+
+// VoteRound is every accusation made in one round of voting and its outcome
+type VoteRound struct {
+	Round       int    `json:"round"`
+	Votes       []Vote `json:"votes"`
+	EjectedID   string `json:"ejected_id,omitempty"`
+	Ejected     string `json:"ejected,omitempty"`
+	WasImpostor bool   `json:"was_impostor"`
+}
+
+// Vote is a single accusation; an empty suspect is a skip vote
+type Vote struct {
+	Voter   string `json:"voter"`
+	Suspect string `json:"suspect,omitempty"`
+}
@@ -0,0 +1,51 @@
+package api
+
+// Error codes sent in Error.Code. Clients should check the code; the
+// message is for people and may be reworded.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidJSON      = "invalid_json"
+	CodeInvalidRequest   = "invalid_request" // a field failed Validate; see Error.Field
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeTooLarge         = "payload_too_large"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+	CodeUnavailable      = "unavailable"
+
+	// Game rules a client may want to explain in its own words
+	CodeRoomNotFound     = "room_not_found"
+	CodeRoomFull         = "room_full"
+	CodeNameTaken        = "name_taken"
+	CodeNotOnRoster      = "not_on_roster"
+	CodeGameInProgress   = "game_in_progress"
+	CodeNoGame           = "no_game"
+	CodeNoMeeting        = "no_meeting"
+	CodeAlreadyVoted     = "already_voted"
+	CodeNotEnoughPlayers = "not_enough_players"
+	CodeChatNotAllowed   = "chat_not_allowed"
+	CodeMessageTooLong   = "message_too_long"
+)
+
+// ErrorResponse is the body of every error response:
+//
+//	{"error": {"code": "room_full", "message": "Room is full", "request_id": "…"}}
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+// Error describes what went wrong. The request ID lets a student quote
+// the error and the teacher find the matching log lines.
+type Error struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Field     string `json:"field,omitempty"` // the request field at fault, for invalid_request
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
@@ -0,0 +1,71 @@
+package api
+
+import "time"
+
+// Health describes how students can reach the server
+type Health struct {
+	Status     string         `json:"status"`   // healthy or degraded
+	Database   string         `json:"database"` // ok or unavailable
+	Time       time.Time      `json:"time"`
+	LocalIP    string         `json:"local_ip"`
+	LocalURL   string         `json:"local_url"`
+	MDNSURL    string         `json:"mdns_url"`
+	Port       string         `json:"port"`
+	TLSMode    string         `json:"tls_mode"`
+	Protocol   string         `json:"protocol"`
+	Interfaces []InterfaceURL `json:"interfaces"`
+}
+
+// NetworkInterface is an address students might use to reach the server
+type NetworkInterface struct {
+	Name  string `json:"name"`
+	IP    string `json:"ip"`
+	Kind  string `json:"kind"`
+	Score int    `json:"score"`
+}
+
+// InterfaceURL is an address and the URL students would open with it
+type InterfaceURL struct {
+	NetworkInterface
+	URL string `json:"url"`
+}
+
+// Liveness reports that the process is up
+type Liveness struct {
+	Status        string `json:"status"`
+	UptimeSeconds int    `json:"uptime_seconds"`
+}
+
+// Readiness reports whether the server can handle games
+type Readiness struct {
+	Status   string         `json:"status"` // ready or not_ready
+	Time     time.Time      `json:"time"`
+	Database DatabaseHealth `json:"database"`
+	Pool     PoolStats      `json:"pool"`
+	Game     *GameStats     `json:"game,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// DatabaseHealth is the result of pinging the database
+type DatabaseHealth struct {
+	Status    string  `json:"status"` // ok or unavailable
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// PoolStats are the database/sql connection pool counters
+type PoolStats struct {
+	MaxOpen        int     `json:"max_open"`
+	Open           int     `json:"open"`
+	InUse          int     `json:"in_use"`
+	Idle           int     `json:"idle"`
+	WaitCount      int64   `json:"wait_count"`
+	WaitDurationMs float64 `json:"wait_duration_ms"`
+}
+
+// GameStats counts rooms that haven't finished and the players in them
+type GameStats struct {
+	ActiveRooms      int            `json:"active_rooms"`
+	RoomsByPhase     map[string]int `json:"rooms_by_phase"`
+	ConnectedPlayers int            `json:"connected_players"`
+}
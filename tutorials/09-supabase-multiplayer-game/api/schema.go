@@ -0,0 +1,138 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schemas builds OpenAPI schemas from the types in this package. Named
+// structs are collected by name for the document's components/schemas
+// and referenced with $ref. Fields tagged omitempty are optional; the
+// rest are required. Validate rules become schema keywords, so the
+// document describes what the server accepts.
+type Schemas map[string]any
+
+// For returns the schema for v's type, adding the structs it uses to s
+func (s Schemas) For(v any) map[string]any {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s Schemas) schema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, ok := s[t.Name()]; !ok {
+			s[t.Name()] = map[string]any{} // placeholder while the fields are built
+			s[t.Name()] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	panic(fmt.Sprintf("api: no schema for %s", t))
+}
+
+func (s Schemas) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	s.addFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds t's fields to properties; embedded structs' fields are
+// added as if they were t's own, as encoding/json does
+func (s Schemas) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			s.addFields(field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		// Rules after dive describe the items
+		property := s.schema(field.Type)
+		target := property
+		for _, r := range rules(field) {
+			if r.name == "dive" {
+				target = property["items"].(map[string]any)
+				continue
+			}
+			r.describe(target)
+		}
+		properties[name] = property
+
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// describe adds the schema keywords for the rule
+func (r rule) describe(schema map[string]any) {
+	switch r.name {
+	case "required":
+		if schema["type"] == "array" {
+			schema["minItems"] = 1
+		} else if schema["type"] == "string" {
+			schema["minLength"] = 1
+		}
+	case "max":
+		switch schema["type"] {
+		case "array":
+			schema["maxItems"] = r.number()
+		case "object":
+			schema["maxProperties"] = r.number()
+		default:
+			schema["maxLength"] = r.number()
+		}
+	case "min":
+		schema["minimum"] = r.number()
+	case "uuid":
+		schema["format"] = "uuid"
+	case "roomcode":
+		schema["pattern"] = roomCodePattern.String()
+	case "oneof":
+		schema["enum"] = strings.Fields(r.arg)
+	}
+}
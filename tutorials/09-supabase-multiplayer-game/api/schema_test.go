@@ -0,0 +1,110 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+// property returns a property of a schema collected in s
+func property(t *testing.T, s Schemas, schema, name string) map[string]any {
+	t.Helper()
+
+	object, ok := s[schema].(map[string]any)
+	if !ok {
+		t.Fatalf("no schema %s", schema)
+	}
+	prop, ok := object["properties"].(map[string]any)[name].(map[string]any)
+	if !ok {
+		t.Fatalf("%s has no property %s", schema, name)
+	}
+	return prop
+}
+
+func TestSchemasFor(t *testing.T) {
+	s := Schemas{}
+	ref := s.For(JoinRoomRequest{})
+	if ref["$ref"] != "#/components/schemas/JoinRoomRequest" {
+		t.Fatalf("For = %v, want a $ref", ref)
+	}
+
+	join := s["JoinRoomRequest"].(map[string]any)
+	if required := join["required"]; !reflect.DeepEqual(required, []string{"room_code", "username"}) {
+		t.Errorf("required = %v, want the fields without omitempty", required)
+	}
+	if code := property(t, s, "JoinRoomRequest", "room_code"); code["pattern"] != roomCodePattern.String() || code["minLength"] != 1 {
+		t.Errorf("room_code = %v", code)
+	}
+	if name := property(t, s, "JoinRoomRequest", "username"); name["maxLength"] != 20 {
+		t.Errorf("username = %v, want maxLength 20", name)
+	}
+	color := property(t, s, "JoinRoomRequest", "avatar_color")
+	if enum := color["enum"]; !reflect.DeepEqual(enum, []string{"red", "blue", "green", "yellow", "purple", "orange", "pink", "cyan"}) {
+		t.Errorf("avatar_color enum = %v", enum)
+	}
+
+	s.For(VoteRequest{})
+	if voter := property(t, s, "VoteRequest", "voter_id"); voter["format"] != "uuid" {
+		t.Errorf("voter_id = %v, want format uuid", voter)
+	}
+	if round := property(t, s, "VoteRequest", "round"); round["type"] != "integer" || round["minimum"] != nil {
+		t.Errorf("round = %v, want an integer with no minimum", round)
+	}
+}
+
+func TestSchemasDive(t *testing.T) {
+	s := Schemas{}
+	s.For(CreateSessionRequest{})
+
+	roster := property(t, s, "CreateSessionRequest", "roster")
+	if roster["type"] != "array" || roster["minItems"] != 1 || roster["maxItems"] != 500 {
+		t.Errorf("roster = %v, want 1 to 500 items", roster)
+	}
+	items := roster["items"].(map[string]any)
+	if items["type"] != "string" || items["maxLength"] != 20 {
+		t.Errorf("roster items = %v, want strings of at most 20 characters", items)
+	}
+	if _, ok := roster["maxLength"]; ok {
+		t.Error("the item rule was applied to the list")
+	}
+}
+
+func TestSchemasNested(t *testing.T) {
+	s := Schemas{}
+	s.For(ErrorResponse{})
+
+	if ref := property(t, s, "ErrorResponse", "error")["$ref"]; ref != "#/components/schemas/Error" {
+		t.Errorf("error = %v, want a $ref to Error", ref)
+	}
+	if _, ok := s["Error"]; !ok {
+		t.Fatal("Error wasn't collected")
+	}
+	if field := property(t, s, "Error", "field"); field["type"] != "string" {
+		t.Errorf("field = %v", field)
+	}
+	required := s["Error"].(map[string]any)["required"]
+	if !reflect.DeepEqual(required, []string{"code", "message"}) {
+		t.Errorf("Error required = %v, want code and message", required)
+	}
+}
+
+func TestSchemasTypes(t *testing.T) {
+	s := Schemas{}
+	tests := []struct {
+		v    any
+		want map[string]any
+	}{
+		{"", map[string]any{"type": "string"}},
+		{true, map[string]any{"type": "boolean"}},
+		{0, map[string]any{"type": "integer"}},
+		{int64(0), map[string]any{"type": "integer", "format": "int64"}},
+		{0.5, map[string]any{"type": "number"}},
+		{[]int{}, map[string]any{"type": "array", "items": map[string]any{"type": "integer"}}},
+		{map[string]string{}, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}}},
+		{&CreateRoomRequest{}, map[string]any{"$ref": "#/components/schemas/CreateRoomRequest"}},
+	}
+	for _, tt := range tests {
+		if got := s.For(tt.v); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("For(%T) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
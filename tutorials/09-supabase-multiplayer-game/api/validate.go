@@ -0,0 +1,166 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	roomCodePattern = regexp.MustCompile(`^[A-Z]{6}$`)
+)
+
+// FieldError is a request field that failed Validate
+type FieldError struct {
+	Field   string // the field's JSON name
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// checker is implemented by requests with rules that involve more than
+// one field. It runs after the tag rules pass.
+type checker interface {
+	check() error
+}
+
+// Validate checks a request's fields against their validate tags:
+//
+//	required   set; strings must be more than spaces, lists need an item
+//	max=N      at most N characters, or N items
+//	min=N      a number of at least N
+//	uuid       a UUID
+//	roomcode   six capital letters
+//	oneof=a b  one of the listed values
+//	dive       apply the rules after it to each item of a list
+//
+// Rules other than required and min are skipped for fields left empty.
+// The first failure is returned as a *FieldError. v must point to a
+// struct.
+func Validate(v any) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if label, problem := checkRules(value.Field(i), name, rules(field)); problem != "" {
+			return &FieldError{Field: name, Message: label + " " + problem}
+		}
+	}
+
+	if c, ok := v.(checker); ok {
+		return c.check()
+	}
+	return nil
+}
+
+// rule is one entry in a validate tag, e.g. max=20
+type rule struct {
+	name string
+	arg  string
+}
+
+// rules parses a field's validate tag
+func rules(field reflect.StructField) []rule {
+	tag := field.Tag.Get("validate")
+	if tag == "" {
+		return nil
+	}
+
+	var parsed []rule
+	for _, part := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(part, "=")
+		parsed = append(parsed, rule{name, arg})
+	}
+	return parsed
+}
+
+// checkRules returns the first problem with v, and the label to report
+// it under: name, or name[i] for an item checked after dive
+func checkRules(v reflect.Value, name string, rs []rule) (string, string) {
+	for i, r := range rs {
+		if r.name == "dive" {
+			for j := 0; j < v.Len(); j++ {
+				label, problem := checkRules(v.Index(j), fmt.Sprintf("%s[%d]", name, j), rs[i+1:])
+				if problem != "" {
+					return label, problem
+				}
+			}
+			return "", ""
+		}
+		if problem := r.check(v); problem != "" {
+			return name, problem
+		}
+	}
+	return "", ""
+}
+
+// number is the rule's argument; a bad one is a mistake in this package
+func (r rule) number() int {
+	n, err := strconv.Atoi(r.arg)
+	if err != nil {
+		panic(fmt.Sprintf("api: validate rule %s needs a number", r.name))
+	}
+	return n
+}
+
+// check returns what's wrong with v, or an empty string if it passes
+func (r rule) check(v reflect.Value) string {
+	if r.name != "required" && r.name != "min" && v.IsZero() {
+		return ""
+	}
+
+	switch r.name {
+	case "required":
+		if v.IsZero() || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") ||
+			(v.Kind() == reflect.Slice && v.Len() == 0) {
+			return "is required"
+		}
+	case "max":
+		n := r.number()
+		if v.Kind() == reflect.String && utf8.RuneCountInString(v.String()) > n {
+			return fmt.Sprintf("must be at most %d characters", n)
+		}
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() > n {
+			return fmt.Sprintf("must have at most %d items", n)
+		}
+	case "min":
+		if n := r.number(); v.Int() < int64(n) {
+			return fmt.Sprintf("must be at least %d", n)
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(v.String()) {
+			return "must be a UUID"
+		}
+	case "roomcode":
+		if !roomCodePattern.MatchString(v.String()) {
+			return "must be 6 capital letters"
+		}
+	case "oneof":
+		options := strings.Fields(r.arg)
+		for _, option := range options {
+			if v.String() == option {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(options, ", ")
+	default:
+		panic("api: unknown validate rule " + r.name)
+	}
+	return ""
+}
+
+// jsonName is the name a field has in JSON
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
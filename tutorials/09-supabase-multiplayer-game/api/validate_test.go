@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testUUID = "3f2b8c1e-9d4a-4e6b-8f7c-1a2b3c4d5e6f"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   any
+		field string // the field that fails, or empty if it passes
+	}{
+		{"valid room", &CreateRoomRequest{Username: "ada", AvatarColor: "red"}, ""},
+		{"no color", &CreateRoomRequest{Username: "ada"}, ""},
+		{"missing name", &CreateRoomRequest{}, "username"},
+		{"blank name", &CreateRoomRequest{Username: "   "}, "username"},
+		{"name at max", &CreateRoomRequest{Username: strings.Repeat("a", 20)}, ""},
+		{"name over max", &CreateRoomRequest{Username: strings.Repeat("a", 21)}, "username"},
+		{"max counts characters", &CreateRoomRequest{Username: strings.Repeat("é", 20)}, ""},
+		{"unknown color", &CreateRoomRequest{Username: "ada", AvatarColor: "black"}, "avatar_color"},
+
+		{"valid join", &JoinRoomRequest{RoomCode: "ABCDEF", Username: "ada"}, ""},
+		{"lowercase code", &JoinRoomRequest{RoomCode: "abcdef", Username: "ada"}, "room_code"},
+		{"short code", &JoinRoomRequest{RoomCode: "ABCDE", Username: "ada"}, "room_code"},
+		{"code with digits", &JoinRoomRequest{RoomCode: "ABC123", Username: "ada"}, "room_code"},
+		{"missing code", &JoinRoomRequest{Username: "ada"}, "room_code"},
+
+		{"valid vote", &VoteRequest{VoterID: testUUID, Round: 1}, ""},
+		{"uppercase UUID", &VoteRequest{VoterID: strings.ToUpper(testUUID), Round: 1}, ""},
+		{"bad voter", &VoteRequest{VoterID: "player-1", Round: 1}, "voter_id"},
+		{"bad suspect", &VoteRequest{VoterID: testUUID, SuspectID: "x", Round: 1}, "suspect_id"},
+		{"no round", &VoteRequest{VoterID: testUUID}, ""},
+
+		{"valid task", &TaskRequest{PlayerID: testUUID, TaskID: "task1"}, ""},
+		{"unknown task", &TaskRequest{PlayerID: testUUID, TaskID: "all"}, "task_id"},
+		{"no task", &TaskRequest{PlayerID: testUUID}, "task_id"},
+		{"no caller", &EmergencyRequest{}, "player_id"},
+
+		{"valid session", &CreateSessionRequest{Name: "Period 3", Roster: []string{"ada", "grace"}}, ""},
+		{"empty roster", &CreateSessionRequest{Name: "Period 3", Roster: []string{}}, "roster"},
+		{"roster name too long", &CreateSessionRequest{Name: "Period 3", Roster: []string{"ada", strings.Repeat("g", 21)}}, "roster"},
+		{"roster too long", &CreateSessionRequest{Name: "Period 3", Roster: make([]string, 501)}, "roster"},
+
+		{"message", &MessageRequest{PlayerID: testUUID, Content: "hi"}, ""},
+		{"preset", &MessageRequest{PlayerID: testUUID, PresetID: "sus", Channel: "ghost"}, ""},
+		{"unknown channel", &MessageRequest{PlayerID: testUUID, Content: "hi", Channel: "admin"}, "channel"},
+		{"no content or preset", &MessageRequest{PlayerID: testUUID}, "content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.req)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("Validate = %v, want it to pass", err)
+				}
+				return
+			}
+
+			var field *FieldError
+			if !errors.As(err, &field) {
+				t.Fatalf("Validate = %v, want a *FieldError", err)
+			}
+			if field.Field != tt.field {
+				t.Errorf("failed on %s (%s), want %s", field.Field, field.Message, tt.field)
+			}
+		})
+	}
+}
+
+func TestValidateMessages(t *testing.T) {
+	tests := []struct {
+		req  any
+		want string
+	}{
+		{&CreateRoomRequest{}, "username is required"},
+		{&CreateRoomRequest{Username: strings.Repeat("a", 21)}, "username must be at most 20 characters"},
+		{&CreateRoomRequest{Username: "ada", AvatarColor: "black"}, "avatar_color must be one of red, blue, green, yellow, purple, orange, pink, cyan"},
+		{&JoinRoomRequest{RoomCode: "abc", Username: "ada"}, "room_code must be 6 capital letters"},
+		{&VoteRequest{VoterID: "x", Round: 1}, "voter_id must be a UUID"},
+		{&TaskRequest{PlayerID: testUUID, TaskID: "task6"}, "task_id must be one of task1, task2, task3, task4, task5"},
+		{&CreateSessionRequest{Name: "P3", Roster: make([]string, 501)}, "roster must have at most 500 items"},
+		{&CreateSessionRequest{Name: "P3", Roster: []string{"ada", "", strings.Repeat("g", 21)}}, "roster[2] must be at most 20 characters"},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.req); err == nil || err.Error() != tt.want {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestValidateUnknownRule(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("an unknown rule didn't panic")
+		}
+	}()
+	Validate(&struct {
+		Name string `json:"name" validate:"email"`
+	}{Name: "ada"})
+}
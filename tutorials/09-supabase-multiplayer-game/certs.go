@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"crewmate-crisis/config"
+)
+
+// How long generated certificates last. Browsers reject server
+// certificates valid for more than 398 days.
+const (
+	caValidity     = 10 * 365 * 24 * time.Hour
+	serverValidity = 397 * 24 * time.Hour
+	renewBefore    = 30 * 24 * time.Hour
+)
+
+// caCommonName is the name devices show for the installed CA
+const caCommonName = "Crewmate Crisis Local CA"
+
+// caPermittedIPRanges are the addresses the CA may issue for: loopback
+// and private networks. With its DNS names limited the same way, a
+// leaked CA key can't be used against real websites on devices that
+// installed the CA.
+var caPermittedIPRanges = parseCIDRs(
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+	"::1/128", "fc00::/7",
+)
+
+// CertHosts are the names and addresses a server certificate must cover
+type CertHosts struct {
+	DNSNames []string
+	IPs      []net.IP
+}
+
+// localCertHosts lists localhost, the machine's hostname and every
+// address on the machine's network interfaces
+func localCertHosts() CertHosts {
+	hosts := CertHosts{DNSNames: []string{"localhost"}}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hostname = strings.TrimSuffix(hostname, ".local")
+		hosts.DNSNames = append(hosts.DNSNames, hostname, hostname+".local")
+	}
+
+	hosts.IPs = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return hosts
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		hosts.IPs = append(hosts.IPs, ipnet.IP)
+	}
+	return hosts
+}
+
+// ensureCertificates makes sure the TLS files in cfg exist. On first start
+// it creates a CA and a server certificate signed by it; later starts
+// reuse them, reissuing the server certificate when it's close to
+// expiring or doesn't cover a current address (e.g. a laptop on a new
+// network). Teachers only install the CA once. Certificates that weren't
+// issued by the CA, such as ones from generate-cert.sh, are left alone.
+func ensureCertificates(cfg *config.Config, hosts CertHosts) error {
+	current, err := readCertificate(cfg.TLSCertFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if current != nil {
+		// Someone else's certificate
+		ca, err := readCertificate(cfg.TLSCACertFile)
+		if err != nil || current.CheckSignatureFrom(ca) != nil {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.TLSCertFile), 0o700); err != nil {
+		return err
+	}
+	ca, caKey, err := loadOrCreateCA(cfg.TLSCACertFile, cfg.TLSCAKeyFile, hosts)
+	if err != nil {
+		return fmt.Errorf("certificate authority: %w", err)
+	}
+	hosts = permittedHosts(ca, hosts)
+
+	switch {
+	case current == nil:
+		slog.Info("generating HTTPS certificate", "file", cfg.TLSCertFile)
+	case time.Until(current.NotAfter) < renewBefore:
+		slog.Info("renewing HTTPS certificate", "expires", current.NotAfter.Format("Jan 2, 2006"))
+	case !coversHosts(current, hosts):
+		slog.Info("network addresses changed, reissuing HTTPS certificate")
+	default:
+		return nil
+	}
+
+	return createServerCertificate(cfg.TLSCertFile, cfg.TLSKeyFile, ca, caKey, hosts)
+}
+
+// loadOrCreateCA reads the CA from disk, creating it if it doesn't exist.
+// A new CA may only issue for localhost, .local names, the machine's
+// hostname and private addresses. CAs made before these limits are
+// reused as they are, so devices don't need to install a new one.
+func loadOrCreateCA(certFile, keyFile string, hosts CertHosts) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	cert, err := readCertificate(certFile)
+	if err == nil {
+		key, err := readKey(keyFile)
+		return cert, key, err
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	slog.Info("generating certificate authority", "file", certFile)
+	if err := os.MkdirAll(filepath.Dir(certFile), 0o700); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: caCommonName, Organization: []string{"Crewmate Crisis"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+
+		PermittedDNSDomainsCritical: true,
+		PermittedDNSDomains:         caPermittedDNSDomains(hosts),
+		PermittedIPRanges:           caPermittedIPRanges,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(certFile, "CERTIFICATE", der, 0o644); err != nil {
+		return nil, nil, err
+	}
+	if err := writeKey(keyFile, key); err != nil {
+		return nil, nil, err
+	}
+
+	cert, err = x509.ParseCertificate(der)
+	return cert, key, err
+}
+
+// createServerCertificate issues a certificate for hosts signed by the CA
+func createServerCertificate(certFile, keyFile string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, hosts CertHosts) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: hosts.DNSNames[0], Organization: []string{"Crewmate Crisis"}},
+		DNSNames:     hosts.DNSNames,
+		IPAddresses:  hosts.IPs,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(serverValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	if err := writeKey(keyFile, key); err != nil {
+		return err
+	}
+	return writePEM(certFile, "CERTIFICATE", der, 0o644)
+}
+
+// caPermittedDNSDomains is localhost, every .local name and the machine's
+// own names from hosts
+func caPermittedDNSDomains(hosts CertHosts) []string {
+	domains := []string{"localhost", ".local"}
+	for _, name := range hosts.DNSNames {
+		name = strings.ToLower(name)
+		if !domainPermitted(name, domains) {
+			domains = append(domains, name)
+		}
+	}
+	return domains
+}
+
+// permittedHosts drops the names and addresses ca can't issue for. A
+// server certificate with any of them would be rejected outright, e.g.
+// on a laptop that also has a public IPv6 address.
+func permittedHosts(ca *x509.Certificate, hosts CertHosts) CertHosts {
+	if len(ca.PermittedDNSDomains) == 0 && len(ca.PermittedIPRanges) == 0 {
+		return hosts
+	}
+
+	var permitted CertHosts
+	for _, name := range hosts.DNSNames {
+		if domainPermitted(strings.ToLower(name), ca.PermittedDNSDomains) {
+			permitted.DNSNames = append(permitted.DNSNames, name)
+		}
+	}
+	for _, ip := range hosts.IPs {
+		if ipPermitted(ip, ca.PermittedIPRanges) {
+			permitted.IPs = append(permitted.IPs, ip)
+		} else {
+			slog.Debug("address left off the HTTPS certificate", "ip", ip)
+		}
+	}
+	return permitted
+}
+
+// domainPermitted matches name the way certificate verification applies
+// name constraints: a leading dot means subdomains only
+func domainPermitted(name string, domains []string) bool {
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, ".") {
+			if strings.HasSuffix(name, domain) && len(name) > len(domain) {
+				return true
+			}
+		} else if name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func ipPermitted(ip net.IP, ranges []*net.IPNet) bool {
+	for _, ipRange := range ranges {
+		if ipRange.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// coversHosts reports whether cert is valid for every name and address
+func coversHosts(cert *x509.Certificate, hosts CertHosts) bool {
+	for _, name := range hosts.DNSNames {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	for _, ip := range hosts.IPs {
+		if cert.VerifyHostname(ip.String()) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func readCertificate(file string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s: no PEM certificate", file)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func readKey(file string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM key", file)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ECDSA key", file)
+	}
+	return ecKey, nil
+}
+
+func writeKey(file string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return writePEM(file, "PRIVATE KEY", der, 0o600)
+}
+
+func writePEM(file, blockType string, der []byte, perm os.FileMode) error {
+	var buf bytes.Buffer
+	if err := pem.Encode(&buf, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), perm)
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	ranges := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, ipRange, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		ranges[i] = ipRange
+	}
+	return ranges
+}
+
+func randomSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err)
+	}
+	return serial
+}
+
+// downloadCA serves the CA certificate so teachers can install it on
+// classroom devices and skip the browser's security warning
+func (s *Server) downloadCA(w http.ResponseWriter, r *http.Request) {
+	if _, err := os.Stat(s.config.TLSCACertFile); err != nil {
+		httpError(w, r, "No certificate authority. HTTPS certificates weren't generated by this server.", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-x509-ca-cert")
+	w.Header().Set("Content-Disposition", `attachment; filename="crewmate-crisis-ca.crt"`)
+	http.ServeFile(w, r, s.config.TLSCACertFile)
+}
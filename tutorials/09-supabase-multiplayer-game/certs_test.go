@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"crewmate-crisis/config"
+)
+
+func testCertConfig(t *testing.T) *config.Config {
+	dir := t.TempDir()
+	return &config.Config{
+		TLSCertFile:   filepath.Join(dir, "certs", "server.crt"),
+		TLSKeyFile:    filepath.Join(dir, "certs", "server.key"),
+		TLSCACertFile: filepath.Join(dir, "certs", "ca.crt"),
+		TLSCAKeyFile:  filepath.Join(dir, "certs", "ca.key"),
+	}
+}
+
+func testCertHosts(ips ...string) CertHosts {
+	hosts := CertHosts{DNSNames: []string{"localhost", "laptop", "laptop.local"}}
+	for _, ip := range ips {
+		hosts.IPs = append(hosts.IPs, net.ParseIP(ip))
+	}
+	return hosts
+}
+
+func readTestCertificate(t *testing.T, file string) *x509.Certificate {
+	t.Helper()
+
+	cert, err := readCertificate(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// verifyTestCertificate checks cert chains to ca for host, the way a
+// browser that installed the CA would
+func verifyTestCertificate(cert, ca *x509.Certificate, host string) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	_, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+	return err
+}
+
+func TestEnsureCertificates(t *testing.T) {
+	cfg := testCertConfig(t)
+	hosts := testCertHosts("127.0.0.1", "::1", "192.168.1.20", "203.0.113.5", "2001:db8::20")
+	if err := ensureCertificates(cfg, hosts); err != nil {
+		t.Fatal(err)
+	}
+
+	ca := readTestCertificate(t, cfg.TLSCACertFile)
+	if !ca.IsCA || !ca.MaxPathLenZero || ca.Subject.CommonName != caCommonName {
+		t.Errorf("CA = %s, IsCA %v, MaxPathLenZero %v", ca.Subject, ca.IsCA, ca.MaxPathLenZero)
+	}
+	if !ca.PermittedDNSDomainsCritical {
+		t.Error("CA name constraints aren't critical")
+	}
+	if want := []string{"localhost", ".local", "laptop"}; !slices.Equal(ca.PermittedDNSDomains, want) {
+		t.Errorf("CA PermittedDNSDomains = %q, want %q", ca.PermittedDNSDomains, want)
+	}
+	var ranges []string
+	for _, ipRange := range ca.PermittedIPRanges {
+		ranges = append(ranges, ipRange.String())
+	}
+	if want := []string{"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7"}; !slices.Equal(ranges, want) {
+		t.Errorf("CA PermittedIPRanges = %q, want %q", ranges, want)
+	}
+
+	cert := readTestCertificate(t, cfg.TLSCertFile)
+	if !slices.Equal(cert.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}) || cert.IsCA {
+		t.Errorf("server certificate ExtKeyUsage = %v, IsCA %v", cert.ExtKeyUsage, cert.IsCA)
+	}
+	if cert.NotAfter.Sub(cert.NotBefore) > 398*24*time.Hour {
+		t.Errorf("server certificate lasts %v, longer than browsers accept", cert.NotAfter.Sub(cert.NotBefore))
+	}
+	for _, host := range []string{"localhost", "laptop", "laptop.local", "127.0.0.1", "::1", "192.168.1.20"} {
+		if err := verifyTestCertificate(cert, ca, host); err != nil {
+			t.Errorf("verify %s: %v", host, err)
+		}
+	}
+
+	// Public addresses are left off, or the whole chain would be rejected
+	for _, ip := range cert.IPAddresses {
+		if ip.Equal(net.ParseIP("203.0.113.5")) || ip.Equal(net.ParseIP("2001:db8::20")) {
+			t.Errorf("server certificate includes public address %s", ip)
+		}
+	}
+	if err := verifyTestCertificate(cert, ca, "203.0.113.5"); err == nil {
+		t.Error("verify 203.0.113.5 succeeded")
+	}
+}
+
+func TestEnsureCertificatesReusesCA(t *testing.T) {
+	cfg := testCertConfig(t)
+	if err := ensureCertificates(cfg, testCertHosts("127.0.0.1", "192.168.1.20")); err != nil {
+		t.Fatal(err)
+	}
+	caPEM, _ := os.ReadFile(cfg.TLSCACertFile)
+	caKeyPEM, _ := os.ReadFile(cfg.TLSCAKeyFile)
+	certPEM, _ := os.ReadFile(cfg.TLSCertFile)
+
+	// Same addresses: nothing changes
+	if err := ensureCertificates(cfg, testCertHosts("127.0.0.1", "192.168.1.20")); err != nil {
+		t.Fatal(err)
+	}
+	if same, _ := os.ReadFile(cfg.TLSCertFile); !bytes.Equal(same, certPEM) {
+		t.Error("server certificate reissued for the same addresses")
+	}
+
+	// A new network: the server certificate is reissued by the same CA
+	if err := ensureCertificates(cfg, testCertHosts("127.0.0.1", "10.0.0.5")); err != nil {
+		t.Fatal(err)
+	}
+	if reused, _ := os.ReadFile(cfg.TLSCACertFile); !bytes.Equal(reused, caPEM) {
+		t.Error("CA certificate replaced")
+	}
+	if reused, _ := os.ReadFile(cfg.TLSCAKeyFile); !bytes.Equal(reused, caKeyPEM) {
+		t.Error("CA key replaced")
+	}
+	ca := readTestCertificate(t, cfg.TLSCACertFile)
+	cert := readTestCertificate(t, cfg.TLSCertFile)
+	if err := verifyTestCertificate(cert, ca, "10.0.0.5"); err != nil {
+		t.Errorf("verify 10.0.0.5 after reissue: %v", err)
+	}
+}
+
+func TestEnsureCertificatesLeavesOtherCertificates(t *testing.T) {
+	cfg := testCertConfig(t)
+	if err := ensureCertificates(cfg, testCertHosts("127.0.0.1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A certificate from generate-cert.sh, i.e. not signed by our CA
+	other := testCertConfig(t)
+	if err := ensureCertificates(other, testCertHosts("127.0.0.1")); err != nil {
+		t.Fatal(err)
+	}
+	otherPEM, _ := os.ReadFile(other.TLSCertFile)
+	if err := os.WriteFile(cfg.TLSCertFile, otherPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ensureCertificates(cfg, testCertHosts("127.0.0.1", "192.168.1.20")); err != nil {
+		t.Fatal(err)
+	}
+	if kept, _ := os.ReadFile(cfg.TLSCertFile); !bytes.Equal(kept, otherPEM) {
+		t.Error("certificate from another CA was replaced")
+	}
+}
+
+func TestPermittedHosts(t *testing.T) {
+	// CAs made before name constraints can issue for anything
+	unconstrained := &x509.Certificate{}
+	hosts := testCertHosts("203.0.113.5")
+	if got := permittedHosts(unconstrained, hosts); !slices.Equal(got.DNSNames, hosts.DNSNames) || len(got.IPs) != 1 {
+		t.Errorf("permittedHosts(unconstrained) = %+v, want %+v", got, hosts)
+	}
+
+	ca := &x509.Certificate{PermittedDNSDomains: []string{"localhost", ".local", "laptop"}}
+	tests := map[string]bool{
+		"localhost":       true,
+		"LAPTOP":          true,
+		"crewmate.local":  true,
+		"local":           false,
+		"laptop.example":  false,
+		"evil-laptop":     false,
+		"localhost.evil":  false,
+		"sub.laptop":      true,
+		"notlocalhost":    false,
+		"crewmate.local.": false,
+	}
+	for name, want := range tests {
+		got := permittedHosts(ca, CertHosts{DNSNames: []string{name}})
+		if (len(got.DNSNames) == 1) != want {
+			t.Errorf("permittedHosts(%q) = %q, want permitted %v", name, got.DNSNames, want)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"crewmate-crisis/api"
+)
+
+// Chat channels stored in messages.channel
+const (
+	channelPublic   = "public"   // everyone; living players only during meetings
+	channelGhost    = "ghost"    // dead players only
+	channelImpostor = "impostor" // impostor team, when enabled for the room
+)
+
+// Room phases derived from the room status and meeting state
+const (
+	phaseLobby   = "lobby"
+	phaseTasks   = "tasks"
+	phaseMeeting = "meeting"
+	phaseEnded   = "ended"
+)
+
+// playerTokenHeader carries the token a player gets when they join.
+// Player IDs are public, so private channels need the token as well.
+const playerTokenHeader = "X-Player-Token"
+
+// getMessages page sizes
+const (
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 200
+)
+
+// ChatRoom is the room state needed to apply chat rules
+type ChatRoom struct {
+	ID           string
+	Phase        string
+	ImpostorChat bool
+	PresetsOnly  bool
+}
+
+// ChatMember is a player's state as far as chat rules are concerned
+type ChatMember struct {
+	InRoom     bool
+	IsAlive    bool
+	IsImpostor bool
+}
+
+// roomPhase combines the room status and meeting state into a phase
+func roomPhase(status string, inMeeting bool) string {
+	switch {
+	case status == "waiting":
+		return phaseLobby
+	case status == "finished":
+		return phaseEnded
+	case inMeeting:
+		return phaseMeeting
+	}
+	return phaseTasks
+}
+
+// chatChannel decides which channel a message goes to. An empty request
+// picks the player's default channel: ghost chat for the dead, the public
+// channel for everyone else. It returns an error explaining why the
+// player can't chat right now.
+func chatChannel(room ChatRoom, member ChatMember, requested string) (string, error) {
+	if !member.InRoom {
+		return "", errors.New("You are not in this room")
+	}
+
+	// Before and after a game everyone can talk
+	if room.Phase == phaseLobby || room.Phase == phaseEnded {
+		if requested != "" && requested != channelPublic {
+			return "", errors.New("That channel is only open during a game")
+		}
+		return channelPublic, nil
+	}
+
+	if requested == "" {
+		requested = channelPublic
+		if !member.IsAlive {
+			requested = channelGhost
+		}
+	}
+
+	switch requested {
+	case channelGhost:
+		if member.IsAlive {
+			return "", errors.New("Only ghosts can use ghost chat")
+		}
+	case channelImpostor:
+		if !room.ImpostorChat || !member.IsImpostor {
+			return "", errors.New("Impostor chat is not available")
+		}
+	case channelPublic:
+		if !member.IsAlive {
+			return "", errors.New("Ghosts can't talk to the living. Use ghost chat")
+		}
+		if room.Phase != phaseMeeting {
+			return "", errors.New("Chat opens during emergency meetings")
+		}
+	default:
+		return "", errors.New("Unknown chat channel")
+	}
+
+	return requested, nil
+}
+
+// visibleChannels lists the channels a player can read. Once the game is
+// over every channel is revealed.
+func visibleChannels(room ChatRoom, member ChatMember) []string {
+	channels := []string{channelPublic}
+	if room.Phase == phaseEnded {
+		return append(channels, channelGhost, channelImpostor)
+	}
+	if member.InRoom && !member.IsAlive {
+		channels = append(channels, channelGhost)
+	}
+	if member.InRoom && member.IsImpostor && room.ImpostorChat {
+		channels = append(channels, channelImpostor)
+	}
+	return channels
+}
+
+// chatRoom loads the chat state of a room by code
+func (s *Server) chatRoom(ctx context.Context, roomCode string) (ChatRoom, error) {
+	var room ChatRoom
+	var status string
+	var inMeeting bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, status, meeting_started_at IS NOT NULL, impostor_chat, chat_presets_only
+		FROM game_rooms
+		WHERE room_code = $1`,
+		roomCode).Scan(&room.ID, &status, &inMeeting, &room.ImpostorChat, &room.PresetsOnly)
+
+	room.Phase = roomPhase(status, inMeeting)
+	return room, err
+}
+
+// chatMember loads a player's chat state. Unknown players are returned
+// with InRoom set to false.
+func (s *Server) chatMember(ctx context.Context, roomID, playerID string) (ChatMember, error) {
+	member := ChatMember{}
+	if playerID == "" {
+		return member, nil
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT rp.is_alive, COALESCE(gr.impostor_id = rp.player_id, false)
+		FROM room_players rp
+		JOIN game_rooms gr ON rp.room_id = gr.id
+		WHERE rp.room_id = $1 AND rp.player_id = $2`,
+		roomID, playerID).Scan(&member.IsAlive, &member.IsImpostor)
+
+	if err == sql.ErrNoRows {
+		return member, nil
+	} else if err != nil {
+		return member, err
+	}
+
+	member.InRoom = true
+	return member, nil
+}
+
+// issuePlayerToken creates the secret token returned to a player when
+// they join
+func (s *Server) issuePlayerToken(ctx context.Context, playerID string) (string, error) {
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO player_tokens (player_id, token) VALUES ($1, $2)`,
+		playerID, token)
+	return token, err
+}
+
+// checkPlayerToken reports whether token is the one issued to the player
+func (s *Server) checkPlayerToken(ctx context.Context, playerID, token string) (bool, error) {
+	if playerID == "" || token == "" {
+		return false, nil
+	}
+
+	var issued string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT token FROM player_tokens WHERE player_id = $1`,
+		playerID).Scan(&issued)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(issued), []byte(token)) == 1, nil
+}
+
+// revealImpostor reports whether a request may be told who the impostor
+// is: once the game is over, or when it comes from the impostor, proven
+// by their token. Everyone else only learns their own role.
+func (s *Server) revealImpostor(r *http.Request, status, impostorID, playerID string) (bool, error) {
+	if status == api.StatusFinished {
+		return true, nil
+	}
+	if impostorID == "" || playerID != impostorID {
+		return false, nil
+	}
+	return s.checkPlayerToken(r.Context(), playerID, r.Header.Get(playerTokenHeader))
+}
+
+// requirePlayer checks the request carries playerID's token, answering
+// 401 when it doesn't. Anything done as a player goes through here.
+func (s *Server) requirePlayer(w http.ResponseWriter, r *http.Request, playerID string) bool {
+	verified, err := s.checkPlayerToken(r.Context(), playerID, r.Header.Get(playerTokenHeader))
+	if err != nil {
+		serverError(w, r, "Failed to check player token", err)
+		return false
+	}
+	if !verified {
+		httpError(w, r, "Missing or wrong player token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// startMeeting opens chat for living players. The meeting closes when
+// voting completes or after the configured meeting duration, whichever
+// comes first.
+func (s *Server) startMeeting(ctx context.Context, roomID, gameID string) error {
+	var startedAt time.Time
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE game_rooms SET meeting_started_at = NOW()
+		WHERE id = $1 AND status = $2 AND meeting_started_at IS NULL
+		RETURNING meeting_started_at`,
+		roomID, "playing").Scan(&startedAt)
+
+	if err == sql.ErrNoRows {
+		// Already in a meeting or not playing
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Votes cast in this meeting count for the new round
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE games SET meeting_round = meeting_round + 1 WHERE id = $1`,
+		gameID)
+
+	if err != nil {
+		return err
+	}
+
+	s.timers.AfterFunc(s.config.Game.MeetingDuration, func(ctx context.Context) {
+		if err := s.endMeeting(ctx, roomID, gameID, &startedAt); err != nil {
+			logger(ctx).Error("failed to end meeting", "room_id", roomID, "err", err)
+		}
+	})
+	return nil
+}
+
+// endMeeting closes the meeting in a room. When startedAt is set, only
+// that meeting is closed so a stale timer can't end a later meeting.
+func (s *Server) endMeeting(ctx context.Context, roomID, gameID string, startedAt *time.Time) error {
+	query := `UPDATE game_rooms SET meeting_started_at = NULL WHERE id = $1 AND meeting_started_at IS NOT NULL`
+	args := []interface{}{roomID}
+	if startedAt != nil {
+		query += ` AND meeting_started_at = $2`
+		args = append(args, *startedAt)
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		s.recordEvent(ctx, roomID, gameID, eventMeetingEnded, "", "", nil)
+	}
+	return nil
+}
+
+func (s *Server) updateRoomSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	roomCode := vars["code"]
+
+	// Settings left out of the request are unchanged
+	var req api.RoomSettingsRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if !s.requirePlayer(w, r, req.PlayerID) {
+		return
+	}
+
+	var roomID, hostID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, host_id FROM game_rooms WHERE room_code = $1`,
+		roomCode).Scan(&roomID, &hostID)
+
+	if err == sql.ErrNoRows {
+		apiError(w, r, api.CodeRoomNotFound, "Room not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		serverError(w, r, "Failed to get room", err)
+		return
+	}
+
+	if req.PlayerID != hostID {
+		httpError(w, r, "Only the host can change room settings", http.StatusForbidden)
+		return
+	}
+
+	var settings api.RoomSettings
+	err = s.db.QueryRowContext(ctx, `
+		UPDATE game_rooms
+		SET impostor_chat = COALESCE($1, impostor_chat),
+			chat_presets_only = COALESCE($2, chat_presets_only)
+		WHERE id = $3
+		RETURNING impostor_chat, chat_presets_only`,
+		req.ImpostorChat, req.ChatPresetsOnly, roomID).Scan(&settings.ImpostorChat, &settings.ChatPresetsOnly)
+
+	if err != nil {
+		serverError(w, r, "Failed to update settings", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
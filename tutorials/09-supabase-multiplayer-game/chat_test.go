@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestRoomPhase(t *testing.T) {
+	tests := []struct {
+		status    string
+		inMeeting bool
+		want      string
+	}{
+		{"waiting", false, phaseLobby},
+		{"waiting", true, phaseLobby},
+		{"playing", false, phaseTasks},
+		{"playing", true, phaseMeeting},
+		{"finished", false, phaseEnded},
+		{"finished", true, phaseEnded},
+	}
+	for _, tt := range tests {
+		if got := roomPhase(tt.status, tt.inMeeting); got != tt.want {
+			t.Errorf("roomPhase(%q, %v) = %q, want %q", tt.status, tt.inMeeting, got, tt.want)
+		}
+	}
+}
+
+func TestChatChannel(t *testing.T) {
+	alive := ChatMember{InRoom: true, IsAlive: true}
+	ghost := ChatMember{InRoom: true}
+	impostor := ChatMember{InRoom: true, IsAlive: true, IsImpostor: true}
+	outsider := ChatMember{}
+
+	lobby := ChatRoom{Phase: phaseLobby}
+	tasks := ChatRoom{Phase: phaseTasks}
+	meeting := ChatRoom{Phase: phaseMeeting}
+	ended := ChatRoom{Phase: phaseEnded}
+	withImpostorChat := ChatRoom{Phase: phaseTasks, ImpostorChat: true}
+
+	tests := []struct {
+		name      string
+		room      ChatRoom
+		member    ChatMember
+		requested string
+		want      string // empty when the message is refused
+	}{
+		{"outsider", lobby, outsider, "", ""},
+		{"lobby default", lobby, alive, "", channelPublic},
+		{"lobby public", lobby, alive, channelPublic, channelPublic},
+		{"lobby ghost", lobby, ghost, channelGhost, ""},
+		{"after the game", ended, ghost, "", channelPublic},
+		{"tasks default", tasks, alive, "", ""},
+		{"meeting default", meeting, alive, "", channelPublic},
+		{"meeting public", meeting, alive, channelPublic, channelPublic},
+		{"ghost default", tasks, ghost, "", channelGhost},
+		{"ghost in a meeting", meeting, ghost, channelPublic, ""},
+		{"living in ghost chat", meeting, alive, channelGhost, ""},
+		{"impostor chat enabled", withImpostorChat, impostor, channelImpostor, channelImpostor},
+		{"impostor chat disabled", tasks, impostor, channelImpostor, ""},
+		{"crewmate in impostor chat", withImpostorChat, alive, channelImpostor, ""},
+		{"unknown channel", meeting, alive, "team", ""},
+	}
+	for _, tt := range tests {
+		got, err := chatChannel(tt.room, tt.member, tt.requested)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: chatChannel = %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: chatChannel = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestVisibleChannels(t *testing.T) {
+	alive := ChatMember{InRoom: true, IsAlive: true}
+	ghost := ChatMember{InRoom: true}
+	impostor := ChatMember{InRoom: true, IsAlive: true, IsImpostor: true}
+
+	tasks := ChatRoom{Phase: phaseTasks}
+	withImpostorChat := ChatRoom{Phase: phaseTasks, ImpostorChat: true}
+	ended := ChatRoom{Phase: phaseEnded}
+
+	tests := []struct {
+		name   string
+		room   ChatRoom
+		member ChatMember
+		want   []string
+	}{
+		{"outsider", withImpostorChat, ChatMember{}, []string{channelPublic}},
+		{"crewmate", withImpostorChat, alive, []string{channelPublic}},
+		{"ghost", tasks, ghost, []string{channelPublic, channelGhost}},
+		{"impostor without impostor chat", tasks, impostor, []string{channelPublic}},
+		{"impostor", withImpostorChat, impostor, []string{channelPublic, channelImpostor}},
+		{"dead impostor", withImpostorChat, ChatMember{InRoom: true, IsImpostor: true},
+			[]string{channelPublic, channelGhost, channelImpostor}},
+		{"after the game", ended, ChatMember{}, []string{channelPublic, channelGhost, channelImpostor}},
+	}
+	for _, tt := range tests {
+		if got := visibleChannels(tt.room, tt.member); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: visibleChannels = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRevealImpostor(t *testing.T) {
+	// Only the impostor's own request needs the database to check its token
+	s := &Server{}
+	tests := []struct {
+		status     string
+		impostorID string
+		playerID   string
+		want       bool
+	}{
+		{"finished", "p1", "", true},
+		{"finished", "p1", "p2", true},
+		{"playing", "p1", "", false},
+		{"playing", "p1", "p2", false},
+		{"playing", "", "", false},
+		{"waiting", "", "p1", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/rooms/ABCDEF", nil)
+		got, err := s.revealImpostor(r, tt.status, tt.impostorID, tt.playerID)
+		if err != nil || got != tt.want {
+			t.Errorf("revealImpostor(%q, %q, %q) = %v, %v; want %v", tt.status, tt.impostorID, tt.playerID, got, err, tt.want)
+		}
+	}
+}
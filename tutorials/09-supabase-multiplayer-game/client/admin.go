@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"crewmate-crisis/api"
+)
+
+// Teacher dashboard methods. They need Client.AdminPassword.
+
+// Rooms lists every room
+func (c *Client) Rooms(ctx context.Context) ([]api.RoomSummary, error) {
+	rooms, err := call[[]api.RoomSummary](ctx, c, "GET", "/admin/rooms", nil)
+	if err != nil {
+		return nil, err
+	}
+	return *rooms, nil
+}
+
+// EndRoom ends the room's game and closes it
+func (c *Client) EndRoom(ctx context.Context, roomCode string) (*api.RoomEnded, error) {
+	return call[api.RoomEnded](ctx, c, "POST", "/admin"+roomPath(roomCode, "/end"), nil)
+}
+
+// KickPlayer removes a player from the room
+func (c *Client) KickPlayer(ctx context.Context, roomCode, playerID string) (*api.PlayerKicked, error) {
+	return call[api.PlayerKicked](ctx, c, "DELETE", "/admin"+roomPath(roomCode, "/players/"+url.PathEscape(playerID)), nil)
+}
+
+// WipeChat deletes the room's chat
+func (c *Client) WipeChat(ctx context.Context, roomCode string) (*api.ChatWiped, error) {
+	return call[api.ChatWiped](ctx, c, "DELETE", "/admin"+roomPath(roomCode, "/messages"), nil)
+}
+
+// ModerationLog lists moderated messages, in one room or, when roomCode
+// is empty, all of them
+func (c *Client) ModerationLog(ctx context.Context, roomCode string) ([]api.ModerationEntry, error) {
+	values := url.Values{}
+	setQuery(values, "room", roomCode)
+
+	entries, err := call[[]api.ModerationEntry](ctx, c, "GET", withQuery("/admin/moderation", values), nil)
+	if err != nil {
+		return nil, err
+	}
+	return *entries, nil
+}
+
+// Sessions lists class sessions
+func (c *Client) Sessions(ctx context.Context) ([]api.SessionSummary, error) {
+	sessions, err := call[[]api.SessionSummary](ctx, c, "GET", "/admin/sessions", nil)
+	if err != nil {
+		return nil, err
+	}
+	return *sessions, nil
+}
+
+// CreateSession starts a class session, ending the active one
+func (c *Client) CreateSession(ctx context.Context, req api.CreateSessionRequest) (*api.ClassSession, error) {
+	return call[api.ClassSession](ctx, c, "POST", "/admin/sessions", req)
+}
+
+// EndSession ends a class session
+func (c *Client) EndSession(ctx context.Context, sessionID string) (*api.SessionEnded, error) {
+	return call[api.SessionEnded](ctx, c, "POST", "/admin/sessions/"+url.PathEscape(sessionID)+"/end", nil)
+}
+
+// SessionReport gets a class session's report
+func (c *Client) SessionReport(ctx context.Context, sessionID string) (*api.SessionReport, error) {
+	return call[api.SessionReport](ctx, c, "GET", "/admin/sessions/"+url.PathEscape(sessionID)+"/report", nil)
+}
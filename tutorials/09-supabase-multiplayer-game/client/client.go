@@ -0,0 +1,371 @@
+// Package client calls the game server's API, for bots, load tests and
+// other tools. Requests and responses are the types in package api, and
+// error responses are returned as *api.Error:
+//
+//	c := client.New("http://localhost:8080")
+//	room, err := c.CreateRoom(ctx, api.CreateRoomRequest{Username: "bot"})
+//	if client.ErrorCode(err) == api.CodeNameTaken {
+//		...
+//	}
+//	c.PlayerID, c.PlayerToken = room.PlayerID, room.PlayerToken
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"crewmate-crisis/api"
+)
+
+// Client calls one game server. Its fields shouldn't change once it's in
+// use, so a bot playing as several players needs a Client for each.
+type Client struct {
+	BaseURL       string       // the server, e.g. http://localhost:8080
+	HTTPClient    *http.Client // http.DefaultClient when nil
+	AdminPassword string       // ADMIN_PASSWORD, for the teacher dashboard methods
+	PlayerID      string       // JoinedRoom.PlayerID; with PlayerToken, Room and StartGame tell the impostor it's them
+	PlayerToken   string       // JoinedRoom.PlayerToken, for voting, tasks, meetings, chat and settings
+}
+
+// New returns a client for the server at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// ErrorCode is the code of an error response, e.g. api.CodeRoomFull, or
+// an empty string if err isn't one
+func ErrorCode(err error) string {
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// CreateRoom creates a room with the caller as its host
+func (c *Client) CreateRoom(ctx context.Context, req api.CreateRoomRequest) (*api.JoinedRoom, error) {
+	return call[api.JoinedRoom](ctx, c, "POST", "/rooms", req)
+}
+
+// JoinRoom joins a room by its code
+func (c *Client) JoinRoom(ctx context.Context, req api.JoinRoomRequest) (*api.JoinedRoom, error) {
+	return call[api.JoinedRoom](ctx, c, "POST", "/rooms/join", req)
+}
+
+// Room gets a room and its players. ImpostorID is only set for the
+// impostor, or once the game is finished.
+func (c *Client) Room(ctx context.Context, roomCode string) (*api.GameRoom, error) {
+	return call[api.GameRoom](ctx, c, "GET", c.asPlayer(roomPath(roomCode, "")), nil)
+}
+
+// StartGame starts a game in the room, choosing the impostor. ImpostorID
+// is only set when it's the Client's player.
+func (c *Client) StartGame(ctx context.Context, roomCode string) (*api.GameStarted, error) {
+	return call[api.GameStarted](ctx, c, "POST", c.asPlayer(roomPath(roomCode, "/start")), nil)
+}
+
+// Vote casts a vote in the current meeting; leave SuspectID empty to skip
+func (c *Client) Vote(ctx context.Context, roomCode string, req api.VoteRequest) (*api.VoteResult, error) {
+	return call[api.VoteResult](ctx, c, "POST", roomPath(roomCode, "/vote"), req)
+}
+
+// CompleteTask marks one of the player's tasks done
+func (c *Client) CompleteTask(ctx context.Context, roomCode string, req api.TaskRequest) (*api.TaskResult, error) {
+	return call[api.TaskResult](ctx, c, "POST", roomPath(roomCode, "/task"), req)
+}
+
+// CallEmergency starts a meeting
+func (c *Client) CallEmergency(ctx context.Context, roomCode string, req api.EmergencyRequest) (*api.MeetingCalled, error) {
+	return call[api.MeetingCalled](ctx, c, "POST", roomPath(roomCode, "/emergency"), req)
+}
+
+// SendMessage sends a chat or quick-chat message
+func (c *Client) SendMessage(ctx context.Context, roomCode string, req api.MessageRequest) (*api.MessageSent, error) {
+	return call[api.MessageSent](ctx, c, "POST", roomPath(roomCode, "/message"), req)
+}
+
+// MessagesQuery chooses the chat messages Messages returns
+type MessagesQuery struct {
+	PlayerID    string // the player reading, which decides the channels returned
+	PlayerToken string // their JoinedRoom.PlayerToken when it isn't the Client's; without either only public chat is returned
+	Limit       int    // page size; the server's default when 0
+	After       string // a cursor from MessagePage.NextCursor; the latest page when empty
+}
+
+// MessagePage is a page of chat messages
+type MessagePage struct {
+	Messages   []api.ChatMessage
+	HasMore    bool   // more messages follow NextCursor
+	NextCursor string // pass as MessagesQuery.After to read on
+}
+
+// Messages reads the room's chat a page at a time
+func (c *Client) Messages(ctx context.Context, roomCode string, query MessagesQuery) (*MessagePage, error) {
+	values := url.Values{}
+	setQuery(values, "player_id", query.PlayerID)
+	setQuery(values, "after", query.After)
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+
+	resp, err := c.send(ctx, "GET", withQuery(roomPath(roomCode, "/messages"), values), nil, playerHeader(query.PlayerToken))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	page := MessagePage{
+		HasMore:    resp.Header.Get("X-Has-More") == "true",
+		NextCursor: resp.Header.Get("X-Next-Cursor"),
+	}
+	if err := decode(resp, &page.Messages); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdateRoomSettings changes the room's chat settings; only the host can
+func (c *Client) UpdateRoomSettings(ctx context.Context, roomCode string, req api.RoomSettingsRequest) (*api.RoomSettings, error) {
+	return call[api.RoomSettings](ctx, c, "PUT", roomPath(roomCode, "/settings"), req)
+}
+
+// Scoreboard gets the room's scoreboard
+func (c *Client) Scoreboard(ctx context.Context, roomCode string) (*api.Scoreboard, error) {
+	return call[api.Scoreboard](ctx, c, "GET", roomPath(roomCode, "/scoreboard"), nil)
+}
+
+// Transcript gets a finished game's transcript; an empty gameID means
+// the room's most recently finished game
+func (c *Client) Transcript(ctx context.Context, roomCode, gameID string) (*api.Transcript, error) {
+	values := url.Values{}
+	setQuery(values, "game_id", gameID)
+
+	return call[api.Transcript](ctx, c, "GET", withQuery(roomPath(roomCode, "/transcript"), values), nil)
+}
+
+// TranscriptMarkdown is Transcript formatted as Markdown for printing
+func (c *Client) TranscriptMarkdown(ctx context.Context, roomCode, gameID string) (string, error) {
+	values := url.Values{"format": {"markdown"}}
+	setQuery(values, "game_id", gameID)
+
+	body, err := c.read(ctx, withQuery(roomPath(roomCode, "/transcript"), values))
+	return string(body), err
+}
+
+// RoomQR gets a PNG QR code that joins the room; size is its width in
+// pixels, or 0 for the server's default
+func (c *Client) RoomQR(ctx context.Context, roomCode string, size int) ([]byte, error) {
+	values := url.Values{}
+	if size > 0 {
+		values.Set("size", strconv.Itoa(size))
+	}
+	return c.read(ctx, withQuery(roomPath(roomCode, "/qr"), values))
+}
+
+// Replay gets a finished game's event log
+func (c *Client) Replay(ctx context.Context, gameID string) (*api.Replay, error) {
+	return call[api.Replay](ctx, c, "GET", "/games/"+url.PathEscape(gameID)+"/replay", nil)
+}
+
+// QuickChat lists the quick-chat phrases
+func (c *Client) QuickChat(ctx context.Context) (*api.QuickChatCatalog, error) {
+	return call[api.QuickChatCatalog](ctx, c, "GET", "/quickchat", nil)
+}
+
+// Health shows how students can reach the server
+func (c *Client) Health(ctx context.Context) (*api.Health, error) {
+	return call[api.Health](ctx, c, "GET", "/health", nil)
+}
+
+// Liveness checks the server is running
+func (c *Client) Liveness(ctx context.Context) (*api.Liveness, error) {
+	return call[api.Liveness](ctx, c, "GET", "/health/live", nil)
+}
+
+// Readiness checks the server can reach the database. The server
+// responds 503 with the same body when it can't, which is returned
+// along with an *api.Error.
+func (c *Client) Readiness(ctx context.Context) (*api.Readiness, error) {
+	resp, err := c.request(ctx, "GET", "/health/ready", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var readiness api.Readiness
+	if resp.StatusCode == http.StatusOK {
+		if err := decode(resp, &readiness); err != nil {
+			return nil, err
+		}
+		return &readiness, nil
+	}
+
+	body := readError(resp)
+	if resp.StatusCode == http.StatusServiceUnavailable && json.Unmarshal(body, &readiness) == nil && readiness.Status != "" {
+		return &readiness, &api.Error{Code: api.CodeUnavailable, Message: "Server isn't ready: " + readiness.Status}
+	}
+	return nil, parseError(resp, body)
+}
+
+// OpenAPI gets the OpenAPI 3 document describing the API
+func (c *Client) OpenAPI(ctx context.Context) ([]byte, error) {
+	return c.read(ctx, "/openapi.json")
+}
+
+// call sends body as JSON, when it isn't nil, and decodes the response
+// as a T
+func call[T any](ctx context.Context, c *Client, method, path string, body any) (*T, error) {
+	resp, err := c.send(ctx, method, path, body, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out := new(T)
+	if err := decode(resp, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// read gets a response body that isn't JSON
+func (c *Client) read(ctx context.Context, path string) ([]byte, error) {
+	resp, err := c.send(ctx, "GET", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	return body, nil
+}
+
+// send is request, returning error responses as an *api.Error
+func (c *Client) send(ctx context.Context, method, path string, body any, header http.Header) (*http.Response, error) {
+	resp, err := c.request(ctx, method, path, body, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// request sends a request to path, relative to /api, with header and
+// the admin password and player token when there are ones
+func (c *Client) request(ctx context.Context, method, path string, body any, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+"/api"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if c.PlayerToken != "" {
+		req.Header.Set("X-Player-Token", c.PlayerToken)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.AdminPassword != "" {
+		req.SetBasicAuth("teacher", c.AdminPassword)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+func decode(resp *http.Response, out any) error {
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// responseError reads an error response
+func responseError(resp *http.Response) error {
+	return parseError(resp, readError(resp))
+}
+
+// readError reads enough of an error response to parse it
+func readError(resp *http.Response) []byte {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return body
+}
+
+// parseError parses an api.ErrorResponse. Responses that aren't one,
+// e.g. from ngrok when the server is down, get a code from their status.
+func parseError(resp *http.Response, body []byte) error {
+	var errResp api.ErrorResponse
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error.Code != "" {
+		return &errResp.Error
+	}
+
+	code := api.CodeBadRequest
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		code = api.CodeNotFound
+	case resp.StatusCode >= http.StatusInternalServerError:
+		code = api.CodeUnavailable
+	}
+	return &api.Error{Code: code, Message: "Unexpected response: " + resp.Status}
+}
+
+// playerHeader carries a player's token, proving requests come from them
+func playerHeader(token string) http.Header {
+	if token == "" {
+		return nil
+	}
+	return http.Header{"X-Player-Token": {token}}
+}
+
+// asPlayer adds the Client's player to path, so the server can tell them
+// what only they may know
+func (c *Client) asPlayer(path string) string {
+	values := url.Values{}
+	setQuery(values, "player_id", c.PlayerID)
+	return withQuery(path, values)
+}
+
+// roomPath is the path of a room endpoint, e.g. /rooms/ABCDEF/start
+func roomPath(roomCode, endpoint string) string {
+	return "/rooms/" + url.PathEscape(roomCode) + endpoint
+}
+
+func setQuery(values url.Values, key, value string) {
+	if value != "" {
+		values.Set(key, value)
+	}
+}
+
+func withQuery(path string, values url.Values) string {
+	if len(values) == 0 {
+		return path
+	}
+	return path + "?" + values.Encode()
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"crewmate-crisis/api"
+)
+
+// newTestClient points a Client at handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(server.URL + "/")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestCall(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/rooms/ABCDEF/vote" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		var req api.VoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.VoterID != "voter" || req.Round != 2 {
+			t.Errorf("body = %+v, %v", req, err)
+		}
+		writeJSON(w, http.StatusOK, api.VoteResult{Status: "vote_recorded"})
+	})
+
+	result, err := c.Vote(context.Background(), "ABCDEF", api.VoteRequest{VoterID: "voter", Round: 2})
+	if err != nil || result.Status != "vote_recorded" {
+		t.Errorf("Vote = %+v, %v", result, err)
+	}
+}
+
+func TestCallAdminPassword(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, password, ok := r.BasicAuth(); !ok || password != "secret" {
+			writeJSON(w, http.StatusUnauthorized, api.ErrorResponse{Error: api.Error{Code: api.CodeUnauthorized, Message: "Unauthorized"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, []api.RoomSummary{{}})
+	})
+
+	if _, err := c.Rooms(context.Background()); ErrorCode(err) != api.CodeUnauthorized {
+		t.Errorf("without the password: err = %v, want unauthorized", err)
+	}
+	c.AdminPassword = "secret"
+	if rooms, err := c.Rooms(context.Background()); err != nil || len(rooms) != 1 {
+		t.Errorf("with the password: Rooms = %v, %v", rooms, err)
+	}
+}
+
+func TestCallPlayerToken(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Player-Token") != "token" {
+			writeJSON(w, http.StatusUnauthorized, api.ErrorResponse{Error: api.Error{Code: api.CodeUnauthorized, Message: "Missing or wrong player token"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, api.TaskResult{Status: "task_completed"})
+	})
+
+	req := api.TaskRequest{PlayerID: "player", TaskID: "task1"}
+	if _, err := c.CompleteTask(context.Background(), "ABCDEF", req); ErrorCode(err) != api.CodeUnauthorized {
+		t.Errorf("without the token: err = %v, want unauthorized", err)
+	}
+	c.PlayerToken = "token"
+	if result, err := c.CompleteTask(context.Background(), "ABCDEF", req); err != nil || result.Status != "task_completed" {
+		t.Errorf("with the token: CompleteTask = %+v, %v", result, err)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   api.Error
+	}{
+		{"JSON error", http.StatusConflict,
+			`{"error":{"code":"name_taken","message":"That name is taken","request_id":"abc123"}}`,
+			api.Error{Code: api.CodeNameTaken, Message: "That name is taken", RequestID: "abc123"}},
+		{"field error", http.StatusBadRequest,
+			`{"error":{"code":"invalid_request","message":"username is required","field":"username"}}`,
+			api.Error{Code: api.CodeInvalidRequest, Message: "username is required", Field: "username"}},
+		{"HTML from a tunnel", http.StatusBadGateway, "<html>Tunnel not found</html>",
+			api.Error{Code: api.CodeUnavailable, Message: "Unexpected response: 502 Bad Gateway"}},
+		{"plain not found", http.StatusNotFound, "404 page not found",
+			api.Error{Code: api.CodeNotFound, Message: "Unexpected response: 404 Not Found"}},
+		{"plain bad request", http.StatusBadRequest, "bad",
+			api.Error{Code: api.CodeBadRequest, Message: "Unexpected response: 400 Bad Request"}},
+		{"JSON without a code", http.StatusInternalServerError, `{"message":"oops"}`,
+			api.Error{Code: api.CodeUnavailable, Message: "Unexpected response: 500 Internal Server Error"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+
+			room, err := c.Room(context.Background(), "ABCDEF")
+			var apiErr *api.Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Room = %v, %v; want an *api.Error", room, err)
+			}
+			if room != nil {
+				t.Errorf("Room returned %+v with the error", room)
+			}
+			if *apiErr != tt.want {
+				t.Errorf("error = %+v, want %+v", *apiErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	if code := ErrorCode(nil); code != "" {
+		t.Errorf("ErrorCode(nil) = %q", code)
+	}
+	if code := ErrorCode(errors.New("network down")); code != "" {
+		t.Errorf("ErrorCode(other error) = %q", code)
+	}
+	wrapped := errors.Join(errors.New("joining"), &api.Error{Code: api.CodeRoomFull})
+	if code := ErrorCode(wrapped); code != api.CodeRoomFull {
+		t.Errorf("ErrorCode(wrapped) = %q, want %q", code, api.CodeRoomFull)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       any
+		wantStatus string // the Readiness returned, or empty for none
+		wantCode   string // the error code, or empty for no error
+	}{
+		{"ready", http.StatusOK, api.Readiness{Status: "ready"}, "ready", ""},
+		{"not ready", http.StatusServiceUnavailable, api.Readiness{Status: "not_ready", Error: "timeout"}, "not_ready", api.CodeUnavailable},
+		{"error response", http.StatusServiceUnavailable,
+			api.ErrorResponse{Error: api.Error{Code: api.CodeUnavailable, Message: "Shutting down"}}, "", api.CodeUnavailable},
+		{"unauthorized", http.StatusUnauthorized,
+			api.ErrorResponse{Error: api.Error{Code: api.CodeUnauthorized, Message: "Unauthorized"}}, "", api.CodeUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/health/ready" {
+					t.Errorf("path = %s", r.URL.Path)
+				}
+				writeJSON(w, tt.status, tt.body)
+			})
+
+			readiness, err := c.Readiness(context.Background())
+			if code := ErrorCode(err); code != tt.wantCode {
+				t.Errorf("error = %v, want code %q", err, tt.wantCode)
+			}
+			if tt.wantStatus == "" {
+				if readiness != nil {
+					t.Errorf("Readiness = %+v, want nil", readiness)
+				}
+			} else if readiness == nil || readiness.Status != tt.wantStatus {
+				t.Errorf("Readiness = %+v, want status %s", readiness, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/api/rooms/ABCDEF/messages" || query.Get("player_id") != "p1" ||
+			query.Get("after") != "m1" || query.Get("limit") != "2" {
+			t.Errorf("request = %s", r.URL)
+		}
+		if token := r.Header.Get("X-Player-Token"); token != "token" {
+			t.Errorf("X-Player-Token = %q", token)
+		}
+		w.Header().Set("X-Has-More", "true")
+		w.Header().Set("X-Next-Cursor", "m3")
+		writeJSON(w, http.StatusOK, []api.ChatMessage{{ID: "m2"}, {ID: "m3"}})
+	})
+
+	page, err := c.Messages(context.Background(), "ABCDEF", MessagesQuery{PlayerID: "p1", PlayerToken: "token", After: "m1", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.HasMore || page.NextCursor != "m3" || len(page.Messages) != 2 || page.Messages[1].ID != "m3" {
+		t.Errorf("page = %+v", page)
+	}
+}
+
+func TestMessagesLastPage(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.Query()) != 0 || r.Header.Get("X-Player-Token") != "" {
+			t.Errorf("request = %s with token %q, want no options", r.URL, r.Header.Get("X-Player-Token"))
+		}
+		writeJSON(w, http.StatusOK, []api.ChatMessage{})
+	})
+
+	page, err := c.Messages(context.Background(), "ABCDEF", MessagesQuery{})
+	if err != nil || page.HasMore || page.NextCursor != "" || len(page.Messages) != 0 {
+		t.Errorf("page = %+v, %v", page, err)
+	}
+}
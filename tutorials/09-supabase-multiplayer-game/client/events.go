@@ -0,0 +1,90 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"crewmate-crisis/api"
+)
+
+// Subscription is a room's event stream, from Subscribe
+type Subscription struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// Subscribe streams the room's game events as they happen. playerID and
+// playerToken, when given, are the player listening, who also receives
+// their own role_assigned event; an empty playerToken uses the Client's.
+// Cancel ctx or call Close to stop.
+//
+//	sub, err := c.Subscribe(ctx, room.RoomCode, room.PlayerID, room.PlayerToken)
+//	...
+//	defer sub.Close()
+//	for {
+//		event, err := sub.Next()
+//		if err != nil {
+//			break
+//		}
+//		...
+//	}
+func (c *Client) Subscribe(ctx context.Context, roomCode, playerID, playerToken string) (*Subscription, error) {
+	values := url.Values{}
+	setQuery(values, "player_id", playerID)
+
+	resp, err := c.send(ctx, "GET", withQuery(roomPath(roomCode, "/events"), values), nil, playerHeader(playerToken))
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+	return &Subscription{body: resp.Body, scanner: scanner}, nil
+}
+
+// Next waits for the next event. It returns io.EOF when the server ends
+// the stream, which it does when shutting down or when the subscriber
+// falls too far behind; subscribe again to carry on, bearing in mind
+// events may have been missed.
+func (s *Subscription) Next() (api.GameEvent, error) {
+	var data strings.Builder
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+
+		// A blank line ends an event. The id and event fields repeat
+		// what's in the data, and lines starting with a colon are
+		// keepalives.
+		if line == "" {
+			if data.Len() == 0 {
+				continue
+			}
+			var event api.GameEvent
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return api.GameEvent{}, fmt.Errorf("decoding event: %w", err)
+			}
+			return event, nil
+		}
+
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(value, " "))
+		}
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return api.GameEvent{}, err
+	}
+	return api.GameEvent{}, io.EOF
+}
+
+// Close stops the stream
+func (s *Subscription) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"crewmate-crisis/api"
+)
+
+func TestSubscription(t *testing.T) {
+	stream := ": keepalive\n\n" +
+		"id: 1\nevent: player_joined\ndata: {\"seq\":1,\"type\":\"player_joined\",\"username\":\"ada\"}\n\n" +
+		": keepalive\n\n" +
+		// A data field split over lines is joined with newlines
+		"id: 2\nevent: vote_cast\ndata: {\"seq\":2,\ndata: \"type\":\"vote_cast\",\ndata:\"data\":{\"round\":1}}\n\n" +
+		"\n\n" +
+		"id: 3\nevent: role_assigned\ndata: {\"seq\":3,\"type\":\"role_assigned\",\"player_id\":\"p1\"}\n\n"
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/rooms/ABCDEF/events" || r.URL.Query().Get("player_id") != "p1" {
+			t.Errorf("request = %s", r.URL)
+		}
+		if token := r.Header.Get("X-Player-Token"); token != "token" {
+			t.Errorf("X-Player-Token = %q", token)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, stream)
+	})
+
+	sub, err := c.Subscribe(context.Background(), "ABCDEF", "p1", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	want := []api.GameEvent{
+		{Seq: 1, Type: "player_joined", Username: "ada"},
+		{Seq: 2, Type: "vote_cast", Data: map[string]interface{}{"round": float64(1)}},
+		{Seq: 3, Type: "role_assigned", PlayerID: "p1"},
+	}
+	for _, expected := range want {
+		event, err := sub.Next()
+		if err != nil {
+			t.Fatalf("Next = %v, want event %d", err, expected.Seq)
+		}
+		if event.Seq != expected.Seq || event.Type != expected.Type || event.Username != expected.Username || event.PlayerID != expected.PlayerID ||
+			len(event.Data) != len(expected.Data) || (expected.Data != nil && event.Data["round"] != expected.Data["round"]) {
+			t.Errorf("Next = %+v, want %+v", event, expected)
+		}
+	}
+
+	if _, err := sub.Next(); err != io.EOF {
+		t.Errorf("Next at the end of the stream = %v, want io.EOF", err)
+	}
+}
+
+func TestSubscriptionBadEvent(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "data: {not json\n\n")
+	})
+
+	sub, err := c.Subscribe(context.Background(), "ABCDEF", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	if _, err := sub.Next(); err == nil || !strings.Contains(err.Error(), "decoding event") {
+		t.Errorf("Next = %v, want a decoding error", err)
+	}
+}
+
+func TestSubscribeError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.Query()) != 0 {
+			t.Errorf("request = %s, want no player_id", r.URL)
+		}
+		writeJSON(w, http.StatusNotFound, api.ErrorResponse{Error: api.Error{Code: api.CodeRoomNotFound, Message: "Room not found"}})
+	})
+
+	sub, err := c.Subscribe(context.Background(), "ABCDEF", "", "")
+	if sub != nil || ErrorCode(err) != api.CodeRoomNotFound {
+		t.Errorf("Subscribe = %v, %v; want room_not_found", sub, err)
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	done := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	})
+	defer close(done)
+
+	sub, err := c.Subscribe(context.Background(), "ABCDEF", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.Close()
+	if _, err := sub.Next(); err == nil {
+		t.Error("Next after Close succeeded")
+	}
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envNames are the variables Load reads
+var envNames = []string{
+	"DATABASE_URL", "PORT", "HTTPS_PORT", "TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_AUTO_CERT",
+	"TLS_CA_CERT_FILE", "TLS_CA_KEY_FILE", "HTTPS_REDIRECT", "ADMIN_PASSWORD", "MDNS_ENABLED",
+	"MDNS_HOSTNAME", "REQUEST_TIMEOUT_SECONDS", "SHUTDOWN_TIMEOUT_SECONDS", "LOG_LEVEL", "LOG_FORMAT",
+	"DB_CONNECT_TIMEOUT_SECONDS", "DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS",
+	"DB_CONN_MAX_LIFETIME_SECONDS", "DB_CONN_MAX_IDLE_SECONDS",
+	"SUPABASE_URL", "SUPABASE_PROXY_TARGET", "SUPABASE_PROXY_ALLOW", "SUPABASE_PROXY_TIMEOUT_SECONDS",
+	"SUPABASE_PROXY_ANON_KEY", "SUPABASE_PROXY_MAX_BODY_BYTES",
+	"CORS_ALLOWED_ORIGINS", "CORS_ALLOWED_METHODS", "CORS_ALLOWED_HEADERS", "CORS_ALLOW_CREDENTIALS",
+	"NGROK_URL", "NGROK_DETECT", "NGROK_API",
+	"MAX_PLAYERS", "MEETING_SECONDS", "CHAT_MAX_LENGTH", "CHAT_BLOCKED_WORDS",
+	"CHAT_RATE_LIMIT", "CHAT_RATE_WINDOW_SECONDS",
+}
+
+// setEnv clears every variable Load reads, so the developer's .envrc
+// doesn't leak into the test, then sets vars
+func setEnv(t *testing.T, vars map[string]string) {
+	t.Helper()
+	for _, name := range envNames {
+		t.Setenv(name, "") // empty counts as unset
+	}
+	for name, value := range vars {
+		t.Setenv(name, value)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setEnv(t, nil)
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.DatabaseURL != DefaultDatabaseURL || cfg.Port != DefaultPort || cfg.HTTPSPort != DefaultHTTPSPort {
+		t.Errorf("DatabaseURL, Port, HTTPSPort = %q, %d, %d", cfg.DatabaseURL, cfg.Port, cfg.HTTPSPort)
+	}
+	if !cfg.TLSAutoCert || !cfg.HTTPSRedirect || !cfg.MDNSEnabled || cfg.MDNSHostname != DefaultMDNSHostname {
+		t.Errorf("TLSAutoCert %v, HTTPSRedirect %v, MDNS %v %q", cfg.TLSAutoCert, cfg.HTTPSRedirect, cfg.MDNSEnabled, cfg.MDNSHostname)
+	}
+	if cfg.LogLevel != slog.LevelInfo || cfg.LogFormat != "text" {
+		t.Errorf("log = %v %q", cfg.LogLevel, cfg.LogFormat)
+	}
+	if cfg.RequestTimeout != 10*time.Second || cfg.DB.ConnectTimeout != time.Minute {
+		t.Errorf("RequestTimeout %v, DB.ConnectTimeout %v", cfg.RequestTimeout, cfg.DB.ConnectTimeout)
+	}
+	if cfg.Proxy.Target.String() != DefaultSupabaseTarget {
+		t.Errorf("Proxy.Target = %v", cfg.Proxy.Target)
+	}
+	if got := formatRules(cfg.Proxy.Allow); got != DefaultProxyAllow {
+		t.Errorf("Proxy.Allow = %q, want %q", got, DefaultProxyAllow)
+	}
+	if !slices.Equal(cfg.CORS.Methods, DefaultCORSMethods) || cfg.CORS.Origins != nil {
+		t.Errorf("CORS = %+v", cfg.CORS)
+	}
+	if cfg.CORS.NgrokURL != "" || !cfg.CORS.NgrokDetect || cfg.CORS.NgrokAPI != DefaultNgrokAPI {
+		t.Errorf("ngrok = %q, detect %v, %q", cfg.CORS.NgrokURL, cfg.CORS.NgrokDetect, cfg.CORS.NgrokAPI)
+	}
+	if cfg.Game.MaxPlayers != 10 || cfg.Game.MeetingDuration != 2*time.Minute || cfg.Game.ChatRateLimit != 5 {
+		t.Errorf("Game = %+v", cfg.Game)
+	}
+}
+
+func TestLoadEnvironment(t *testing.T) {
+	setEnv(t, map[string]string{
+		"PORT":                    " 9090 ",
+		"HTTPS_REDIRECT":          "false",
+		"LOG_LEVEL":               "debug",
+		"LOG_FORMAT":              "json",
+		"MEETING_SECONDS":         "90",
+		"SUPABASE_URL":            "https://example.supabase.co",
+		"CORS_ALLOWED_ORIGINS":    " https://a.example, ,https://b.example ",
+		"CHAT_BLOCKED_WORDS":      "sus,vent",
+		"NGROK_URL":               "https://abc123.ngrok-free.app",
+		"NGROK_DETECT":            "0",
+		"DB_MAX_OPEN_CONNS":       "0",
+		"SUPABASE_PROXY_ANON_KEY": "anon",
+	})
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 9090 || cfg.HTTPSRedirect || cfg.LogLevel != slog.LevelDebug || cfg.LogFormat != "json" {
+		t.Errorf("Port %d, HTTPSRedirect %v, log %v %q", cfg.Port, cfg.HTTPSRedirect, cfg.LogLevel, cfg.LogFormat)
+	}
+	if cfg.Game.MeetingDuration != 90*time.Second {
+		t.Errorf("MeetingDuration = %v, want 90s", cfg.Game.MeetingDuration)
+	}
+	// The proxy falls back to SUPABASE_URL
+	if cfg.Proxy.Target.String() != "https://example.supabase.co" {
+		t.Errorf("Proxy.Target = %v", cfg.Proxy.Target)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !slices.Equal(cfg.CORS.Origins, want) {
+		t.Errorf("CORS.Origins = %q, want %q", cfg.CORS.Origins, want)
+	}
+	if want := []string{"sus", "vent"}; !slices.Equal(cfg.Game.BlockedWords, want) {
+		t.Errorf("BlockedWords = %q, want %q", cfg.Game.BlockedWords, want)
+	}
+	if cfg.CORS.NgrokURL != "https://abc123.ngrok-free.app" || cfg.CORS.NgrokDetect {
+		t.Errorf("ngrok = %q, detect %v", cfg.CORS.NgrokURL, cfg.CORS.NgrokDetect)
+	}
+	if cfg.DB.MaxOpenConns != 0 || cfg.Proxy.AnonKey != "anon" {
+		t.Errorf("MaxOpenConns %d, AnonKey %q", cfg.DB.MaxOpenConns, cfg.Proxy.AnonKey)
+	}
+}
+
+func TestLoadFlagsOverrideEnvironment(t *testing.T) {
+	setEnv(t, map[string]string{"PORT": "9090", "MAX_PLAYERS": "6", "NGROK_URL": "https://old.ngrok-free.app"})
+	cfg, err := Load([]string{"-port", "9191", "-meeting", "45s", "-ngrok-url", "https://new.ngrok-free.app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9191 || cfg.Game.MeetingDuration != 45*time.Second || cfg.Game.MaxPlayers != 6 {
+		t.Errorf("Port %d, MeetingDuration %v, MaxPlayers %d", cfg.Port, cfg.Game.MeetingDuration, cfg.Game.MaxPlayers)
+	}
+	if cfg.CORS.NgrokURL != "https://new.ngrok-free.app" {
+		t.Errorf("NgrokURL = %q", cfg.CORS.NgrokURL)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		want string
+	}{
+		{"not a number", map[string]string{"PORT": "eighty"}, `PORT="eighty" is not a number`},
+		{"not a bool", map[string]string{"MDNS_ENABLED": "maybe"}, `MDNS_ENABLED="maybe" is not true or false`},
+		{"bad log level", map[string]string{"LOG_LEVEL": "loud"}, `LOG_LEVEL="loud" is not debug`},
+		{"every problem reported", map[string]string{"PORT": "x", "MAX_PLAYERS": "y"}, `PORT="x" is not a number; MAX_PLAYERS="y" is not a number`},
+		{"bad proxy target", map[string]string{"SUPABASE_PROXY_TARGET": "localhost:54321"}, "invalid Supabase proxy target"},
+		{"bad proxy rule", map[string]string{"SUPABASE_PROXY_ALLOW": "GET rest/v1/"}, "invalid proxy rule"},
+		{"port out of range", map[string]string{"PORT": "70000"}, "PORT 70000 is not a valid port"},
+		{"same ports", map[string]string{"PORT": "8443"}, "PORT and HTTPS_PORT must differ"},
+		{"database URL", map[string]string{"DATABASE_URL": "mysql://localhost/game"}, "DATABASE_URL must be a postgres:// URL"},
+		{"mDNS hostname", map[string]string{"MDNS_HOSTNAME": "crewmate.local"}, "MDNS_HOSTNAME"},
+		{"log format", map[string]string{"LOG_FORMAT": "xml"}, "LOG_FORMAT must be text or json"},
+		{"idle above open", map[string]string{"DB_MAX_OPEN_CONNS": "5", "DB_MAX_IDLE_CONNS": "6"}, "DB_MAX_IDLE_CONNS can't be more"},
+		{"max players", map[string]string{"MAX_PLAYERS": "1"}, "MAX_PLAYERS must be at least 2"},
+		{"wildcard with credentials", map[string]string{"CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"}, `can't include "*"`},
+		{"ngrok URL without https", map[string]string{"NGROK_URL": "http://abc123.ngrok-free.app"}, "NGROK_URL must be an origin"},
+		{"ngrok URL with a path", map[string]string{"NGROK_URL": "https://abc123.ngrok-free.app/game"}, "NGROK_URL must be an origin"},
+		{"ngrok URL with a query", map[string]string{"NGROK_URL": "https://abc123.ngrok-free.app?x=1"}, "NGROK_URL must be an origin"},
+		{"ngrok URL without a host", map[string]string{"NGROK_URL": "https://"}, "NGROK_URL must be an origin"},
+		{"ngrok API", map[string]string{"NGROK_API": "127.0.0.1:4040"}, "NGROK_API must be an http:// URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.vars)
+			cfg, err := Load(nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load = %+v, %v; want an error containing %q", cfg, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadNgrok(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		want string
+	}{
+		{"URL with trailing slash", map[string]string{"NGROK_URL": "https://abc123.ngrok-free.app/"}, "https://abc123.ngrok-free.app/"},
+		{"detect", nil, "ngrok tunnels from " + DefaultNgrokAPI},
+		{"bad API ignored when not detecting", map[string]string{"NGROK_DETECT": "false", "NGROK_API": "nope"}, "no ngrok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.vars)
+			cfg, err := Load(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.CORS.ngrok(); got != tt.want {
+				t.Errorf("ngrok() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProxyRules(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []ProxyRule
+		wantErr bool
+	}{
+		{"GET /rest/v1/", []ProxyRule{{[]string{"GET"}, "/rest/v1/"}}, false},
+		{"get|head /rest/v1/, GET /realtime/v1/", []ProxyRule{
+			{[]string{"GET", "HEAD"}, "/rest/v1/"},
+			{[]string{"GET"}, "/realtime/v1/"},
+		}, false},
+		{" , GET /rest/v1/ ,", []ProxyRule{{[]string{"GET"}, "/rest/v1/"}}, false},
+		{"", nil, true},
+		{" , ", nil, true},
+		{"/rest/v1/", nil, true},
+		{"GET rest/v1/", nil, true},
+		{"GET /rest/v1/ extra", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseProxyRules(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseProxyRules(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !slices.EqualFunc(got, tt.want, func(a, b ProxyRule) bool {
+			return a.Prefix == b.Prefix && slices.Equal(a.Methods, b.Methods)
+		}) {
+			t.Errorf("ParseProxyRules(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestLogValueHidesSecrets(t *testing.T) {
+	setEnv(t, map[string]string{
+		"DATABASE_URL":            "postgres://postgres:hunter2@db:5432/postgres",
+		"ADMIN_PASSWORD":          "letmein",
+		"SUPABASE_PROXY_ANON_KEY": "secret-anon-key",
+	})
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logged := cfg.LogValue().String()
+	for _, secret := range []string{"hunter2", "letmein", "secret-anon-key"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("LogValue includes %q: %s", secret, logged)
+		}
+	}
+}
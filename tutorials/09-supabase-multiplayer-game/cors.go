@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/handlers"
+
+	"crewmate-crisis/config"
+)
+
+// Response headers scripts may read: message paging and PostgREST counts
+var defaultCORSExposedHeaders = []string{"X-Has-More", "X-Next-Cursor", "Content-Range", requestIDHeader}
+
+// CORSPolicy is the cross-origin policy shared by the game API and the
+// Supabase proxy. Origins are exact (https://example.com), "*" for any
+// origin, or a wildcard host (https://*.example.com).
+type CORSPolicy struct {
+	Origins          []string
+	Methods          []string
+	Headers          []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	Tunnels          *ngrokTunnels // this server's ngrok tunnels, also allowed; nil for none
+}
+
+// newCORSPolicy builds the policy from the configured settings, adding
+// the server's own LAN origins and its ngrok URL. Other ngrok domains
+// are only allowed once Tunnels finds them pointing at this server.
+func newCORSPolicy(cfg config.CORSConfig, lanOrigins []string) *CORSPolicy {
+	policy := &CORSPolicy{
+		Methods:          cfg.Methods,
+		Headers:          cfg.Headers,
+		ExposedHeaders:   defaultCORSExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+	}
+	policy.Origins = append(policy.Origins, cfg.Origins...)
+	policy.Origins = append(policy.Origins, lanOrigins...)
+	if cfg.NgrokURL != "" {
+		policy.Origins = append(policy.Origins, strings.TrimSuffix(cfg.NgrokURL, "/"))
+	}
+	return policy
+}
+
+// Allowed reports whether requests from origin may be answered
+func (p *CORSPolicy) Allowed(origin string) bool {
+	for _, allowed := range p.Origins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+
+		// Wildcard host: the scheme must match and the origin must be a
+		// subdomain of the rest
+		i := strings.Index(allowed, "*.")
+		if i < 0 {
+			continue
+		}
+		prefix, suffix := allowed[:i], allowed[i+1:]
+		if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			sub := origin[len(prefix) : len(origin)-len(suffix)]
+			if sub != "" && !strings.ContainsAny(sub, "/:@") {
+				return true
+			}
+		}
+	}
+	return p.Tunnels != nil && p.Tunnels.Allowed(origin)
+}
+
+// Handler applies the policy to next, answering preflight requests itself
+func (p *CORSPolicy) Handler(next http.Handler) http.Handler {
+	options := []handlers.CORSOption{
+		handlers.AllowedOriginValidator(p.Allowed),
+		handlers.AllowedMethods(p.Methods),
+		handlers.AllowedHeaders(p.Headers),
+		handlers.ExposedHeaders(p.ExposedHeaders),
+	}
+	if p.AllowCredentials {
+		options = append(options, handlers.AllowCredentials())
+	}
+	cors := handlers.CORS(options...)(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The allowed origin is echoed back, so caches must key on it
+		w.Header().Add("Vary", "Origin")
+		cors.ServeHTTP(w, r)
+	})
+}
+
+// lanOrigins lists the origins pages served by this server can have when
+// opened by localhost, LAN address or mDNS name
+func lanOrigins(port, httpsPort string, hosts ...string) []string {
+	var origins []string
+	for _, host := range append([]string{"localhost", "127.0.0.1"}, hosts...) {
+		origins = append(origins, "http://"+host+":"+port, "https://"+host+":"+httpsPort)
+	}
+	return origins
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"crewmate-crisis/config"
+)
+
+func TestCORSPolicyAllowed(t *testing.T) {
+	policy := &CORSPolicy{Origins: []string{
+		"https://game.school.edu",
+		"http://localhost:8080",
+		"https://*.example.com",
+	}}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://game.school.edu", true},
+		{"http://localhost:8080", true},
+
+		// Ports and schemes must match exactly
+		{"https://game.school.edu:8443", false},
+		{"http://game.school.edu", false},
+		{"http://localhost:3000", false},
+		{"http://localhost", false},
+		{"https://localhost:8080", false},
+
+		// Wildcards cover subdomains, not the domain itself or lookalikes
+		{"https://app.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"https://.example.com", false},
+		{"https://evil-example.com", false},
+		{"https://example.com.evil.com", false},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://evil.com/.example.com", false},
+		{"https://user@app.example.com", false},
+		{"https://evil.com:1@app.example.com", false},
+
+		{"", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := policy.Allowed(tt.origin); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCORSPolicyAllowedAny(t *testing.T) {
+	policy := &CORSPolicy{Origins: []string{"*"}}
+	for _, origin := range []string{"https://example.com", "http://192.168.1.20:8080"} {
+		if !policy.Allowed(origin) {
+			t.Errorf("Allowed(%q) = false with *", origin)
+		}
+	}
+}
+
+func TestNewCORSPolicy(t *testing.T) {
+	cfg := config.CORSConfig{
+		Origins:  []string{"https://game.school.edu"},
+		NgrokURL: "https://crewmate.ngrok-free.app/",
+	}
+	policy := newCORSPolicy(cfg, lanOrigins("8080", "8443", "192.168.1.20", "crewmate.local"))
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://game.school.edu", true},
+		{"http://192.168.1.20:8080", true},
+		{"https://192.168.1.20:8443", true},
+		{"http://crewmate.local:8080", true},
+		{"https://crewmate.ngrok-free.app", true},
+		{"http://crewmate.ngrok-free.app", false},
+		// Only the configured tunnel, not every ngrok domain
+		{"https://abc123.ngrok-free.app", false},
+		{"https://abc123.ngrok.io", false},
+		{"http://192.168.1.21:8080", false},
+		{"http://192.168.1.20:8443", false},
+	}
+	for _, tt := range tests {
+		if got := policy.Allowed(tt.origin); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestLANOrigins(t *testing.T) {
+	got := lanOrigins("8080", "8443", "192.168.1.20")
+	want := []string{
+		"http://localhost:8080", "https://localhost:8443",
+		"http://127.0.0.1:8080", "https://127.0.0.1:8443",
+		"http://192.168.1.20:8080", "https://192.168.1.20:8443",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("lanOrigins = %q, want %q", got, want)
+	}
+}
+
+func TestCORSPolicyHandler(t *testing.T) {
+	policy := &CORSPolicy{
+		Origins: []string{"https://game.school.edu"},
+		Methods: []string{"GET", "POST"},
+		Headers: []string{"Content-Type"},
+	}
+	handler := policy.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for origin, want := range map[string]string{
+		"https://game.school.edu": "https://game.school.edu",
+		"https://evil.com":        "",
+	} {
+		req := httptest.NewRequest("GET", "/api/health", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("origin %s: Access-Control-Allow-Origin = %q, want %q", origin, got, want)
+		}
+		if rec.Header().Get("Vary") != "Origin" {
+			t.Errorf("origin %s: Vary = %q, want Origin", origin, rec.Header().Get("Vary"))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+
+	"crewmate-crisis/config"
+)
+
+// Delay between connection attempts while the database starts
+const (
+	dbRetryInitial = 500 * time.Millisecond
+	dbRetryMax     = 5 * time.Second
+)
+
+// openDatabase connects to PostgreSQL with the configured pool settings.
+// Supabase can take a while to boot after `supabase start`, so a failed
+// ping is retried with backoff until cfg.DB.ConnectTimeout has passed or
+// ctx is cancelled (e.g. by Ctrl+C).
+func openDatabase(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	connector, err := pq.NewConnector(cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(metricsConnector{connector})
+
+	db.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DB.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.DB.ConnectTimeout)
+	defer cancel()
+
+	delay := dbRetryInitial
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancelPing := context.WithTimeout(ctx, dbRetryMax)
+		err = db.PingContext(pingCtx)
+		cancelPing()
+		if err == nil {
+			break
+		}
+
+		slog.Warn("database not ready", "attempt", attempt, "err", err, "retry_in", delay)
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, fmt.Errorf("database not ready after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, dbRetryMax)
+	}
+
+	stats := db.Stats()
+	slog.Info("database connected",
+		"max_open", stats.MaxOpenConnections,
+		"max_idle", cfg.DB.MaxIdleConns,
+		"lifetime", cfg.DB.ConnMaxLifetime,
+		"idle_timeout", cfg.DB.ConnMaxIdleTime)
+	return db, nil
+}
+
+// metricsConnector opens pq connections that count their errors in
+// crewmate_db_errors_total, so every query is covered without changing
+// each call site
+type metricsConnector struct {
+	*pq.Connector
+}
+
+func (c metricsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, countDBError("connect", err)
+	}
+	return metricsConn{conn}, nil
+}
+
+// metricsConn passes everything to the pq connection it wraps. pq
+// implements each of these interfaces.
+type metricsConn struct {
+	driver.Conn
+}
+
+func (c metricsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	return rows, countDBError("query", err)
+}
+
+func (c metricsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+	return result, countDBError("exec", err)
+}
+
+func (c metricsConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	return stmt, countDBError("prepare", err)
+}
+
+func (c metricsConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+	return tx, countDBError("begin", err)
+}
+
+func (c metricsConn) Ping(ctx context.Context) error {
+	return countDBError("ping", c.Conn.(driver.Pinger).Ping(ctx))
+}
+
+func (c metricsConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c metricsConn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
+
+// countDBError counts err unless it's database/sql asking to retry or
+// the request being abandoned
+func countDBError(operation string, err error) error {
+	if err != nil && !errors.Is(err, driver.ErrSkip) && !errors.Is(err, driver.ErrBadConn) &&
+		!errors.Is(err, context.Canceled) {
+		dbErrors.WithLabelValues(operation).Inc()
+	}
+	return err
+}
+
+// isUniqueViolation reports whether err is PostgreSQL rejecting a row
+// that breaks a unique index
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
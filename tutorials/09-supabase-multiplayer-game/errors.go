@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"crewmate-crisis/api"
+)
+
+// statusCodes is the error code used for each HTTP status when the
+// handler doesn't give a more specific one
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            api.CodeBadRequest,
+	http.StatusUnauthorized:          api.CodeUnauthorized,
+	http.StatusForbidden:             api.CodeForbidden,
+	http.StatusNotFound:              api.CodeNotFound,
+	http.StatusMethodNotAllowed:      api.CodeMethodNotAllowed,
+	http.StatusConflict:              api.CodeConflict,
+	http.StatusRequestEntityTooLarge: api.CodeTooLarge,
+	http.StatusTooManyRequests:       api.CodeRateLimited,
+	http.StatusInternalServerError:   api.CodeInternal,
+	http.StatusBadGateway:            api.CodeUnavailable,
+	http.StatusServiceUnavailable:    api.CodeUnavailable,
+}
+
+// httpError responds with message and the error code for status. Server
+// errors are logged; use serverError when there's an underlying error.
+func httpError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if status >= http.StatusInternalServerError {
+		logger(r.Context()).Error(message, "status", status, "method", r.Method, "path", r.URL.Path)
+	}
+	writeError(w, r, statusCodes[status], message, status)
+}
+
+// apiNotFound answers /api paths that match no route, which would
+// otherwise fall through to the static files
+func apiNotFound(w http.ResponseWriter, r *http.Request) {
+	httpError(w, r, "No such API endpoint", http.StatusNotFound)
+}
+
+// apiMethodNotAllowed answers API routes called with the wrong method
+func apiMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	httpError(w, r, r.Method+" is not allowed here", http.StatusMethodNotAllowed)
+}
+
+// apiError is httpError with a specific error code
+func apiError(w http.ResponseWriter, r *http.Request, code, message string, status int) {
+	writeError(w, r, code, message, status)
+}
+
+// serverError logs err and responds 500 with message. err often comes
+// from the database and may include SQL, so it never reaches the client.
+func serverError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	logger(r.Context()).Error(message, "err", err, "method", r.Method, "path", r.URL.Path)
+	writeError(w, r, api.CodeInternal, message, http.StatusInternalServerError)
+}
+
+// invalidJSON responds to a request body that couldn't be decoded. The
+// decoder's error is only logged, at debug level, as it names Go types.
+func invalidJSON(w http.ResponseWriter, r *http.Request, err error) {
+	logger(r.Context()).Debug("invalid request body", "err", err)
+	writeError(w, r, api.CodeInvalidJSON, "Request body must be valid JSON", http.StatusBadRequest)
+}
+
+// decodeRequest decodes the JSON body into req and checks it with
+// api.Validate, responding 400 and returning false when either fails.
+// An empty body decodes to the zero request, which fails validation
+// unless every field is optional.
+func decodeRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+		invalidJSON(w, r, err)
+		return false
+	}
+
+	err := api.Validate(req)
+	if err == nil {
+		return true
+	}
+
+	apiErr := api.Error{Code: api.CodeInvalidRequest, Message: err.Error()}
+	var field *api.FieldError
+	if errors.As(err, &field) {
+		apiErr.Field = field.Field
+	}
+	writeAPIError(w, r, apiErr, http.StatusBadRequest)
+	return false
+}
+
+// writeError writes an api.ErrorResponse without logging, for callers
+// that log the cause themselves
+func writeError(w http.ResponseWriter, r *http.Request, code, message string, status int) {
+	if code == "" {
+		code = api.CodeBadRequest
+		if status >= http.StatusInternalServerError {
+			code = api.CodeInternal
+		}
+	}
+	writeAPIError(w, r, api.Error{Code: code, Message: message}, status)
+}
+
+func writeAPIError(w http.ResponseWriter, r *http.Request, apiErr api.Error, status int) {
+	apiErr.RequestID = requestID(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(api.ErrorResponse{Error: apiErr})
+}
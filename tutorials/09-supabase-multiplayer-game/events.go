@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"crewmate-crisis/api"
+)
+
+// Event types written to the game_events log
+const (
+	eventPlayerJoined  = "player_joined"
+	eventRoleAssigned  = "role_assigned"
+	eventTaskCompleted = "task_completed" // one per task completed, with its task_id
+	eventPlayerKilled  = "player_killed"  // reserved for the elimination mechanic
+	eventBodyReported  = "body_reported"  // reserved for dead body reporting
+	eventMeetingCalled = "meeting_called"
+	eventMeetingEnded  = "meeting_ended"
+	eventVoteCast      = "vote_cast"
+	eventPlayerEjected = "player_ejected"
+	eventGameEnded     = "game_ended"
+	eventPlayerKicked  = "player_kicked"
+)
+
+// nullString converts an empty string to a SQL NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// recordEvent appends an event to the game log. The log is for review
+// after the game, so failures are logged rather than failing the request.
+// gameID is empty for lobby events that happen before a game starts.
+func (s *Server) recordEvent(ctx context.Context, roomID, gameID, eventType, playerID, targetID string, data map[string]interface{}) {
+	log := logger(ctx).With("event", eventType, "room_id", roomID, "game_id", gameID, "player", playerID)
+	if targetID != "" {
+		log = log.With("target", targetID)
+	}
+
+	var payload []byte
+	if data != nil {
+		var err error
+		payload, err = json.Marshal(data)
+		if err != nil {
+			log.Error("failed to encode event", "err", err)
+			return
+		}
+	}
+
+	event := api.GameEvent{Type: eventType, PlayerID: playerID, TargetID: targetID, Data: data}
+	var username sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		WITH e AS (
+			INSERT INTO game_events (room_id, game_id, event_type, player_id, target_id, data)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, player_id, created_at
+		)
+		SELECT e.id, p.username, e.created_at
+		FROM e
+		LEFT JOIN players p ON e.player_id = p.id`,
+		roomID, nullString(gameID), eventType, nullString(playerID), nullString(targetID), payload,
+	).Scan(&event.Seq, &username, &event.CreatedAt)
+
+	if err != nil {
+		log.Error("failed to record event", "err", err)
+		return
+	}
+	log.Info("game event")
+
+	event.Username = username.String
+	s.events.Publish(roomID, event)
+}
+
+// eventBuffer is how far a stream can fall behind before it's dropped
+const eventBuffer = 64
+
+// eventKeepalive is how often an idle stream sends a comment, so proxies
+// such as ngrok don't close it
+const eventKeepalive = 25 * time.Second
+
+// eventHub sends recorded events to the clients streaming each room. A
+// client that falls eventBuffer events behind is dropped rather than
+// holding up the game; its stream ends, so it knows it missed some.
+type eventHub struct {
+	mu      sync.Mutex
+	streams map[string]map[chan api.GameEvent]struct{} // by room ID
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		streams: make(map[string]map[chan api.GameEvent]struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Subscribe returns the room's future events and a function to stop
+// receiving them. The channel is closed if the subscriber is dropped.
+func (h *eventHub) Subscribe(roomID string) (<-chan api.GameEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan api.GameEvent, eventBuffer)
+	if h.streams[roomID] == nil {
+		h.streams[roomID] = make(map[chan api.GameEvent]struct{})
+	}
+	h.streams[roomID][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.drop(roomID, ch)
+	}
+}
+
+// Publish sends event to the room's subscribers without waiting for them
+func (h *eventHub) Publish(roomID string, event api.GameEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.streams[roomID] {
+		select {
+		case ch <- event:
+		default:
+			h.drop(roomID, ch)
+		}
+	}
+}
+
+// drop removes a subscriber and closes its channel; h.mu must be held
+func (h *eventHub) drop(roomID string, ch chan api.GameEvent) {
+	if _, ok := h.streams[roomID][ch]; !ok {
+		return // already dropped
+	}
+	delete(h.streams[roomID], ch)
+	if len(h.streams[roomID]) == 0 {
+		delete(h.streams, roomID)
+	}
+	close(ch)
+}
+
+// Close ends every stream. Streams never finish on their own, so the
+// HTTP servers call it when shutting down.
+func (h *eventHub) Close() {
+	h.closeOnce.Do(func() { close(h.closed) })
+}
+
+// streamEvents sends a room's game events as they're recorded, as
+// server-sent events named after the event type:
+//
+//	id: 42
+//	event: vote_cast
+//	data: {"seq":42,"type":"vote_cast",...}
+//
+// Roles are secret, so a role_assigned event only goes to the player it
+// names, given by the player_id query parameter along with their token.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	roomCode := vars["code"]
+	playerID := r.URL.Query().Get("player_id")
+	if playerID != "" && !isUUID(playerID) {
+		httpError(w, r, "Invalid player ID", http.StatusBadRequest)
+		return
+	}
+
+	var roomID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id FROM game_rooms WHERE room_code = $1`,
+		roomCode).Scan(&roomID)
+
+	if err == sql.ErrNoRows {
+		apiError(w, r, api.CodeRoomNotFound, "Room not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		serverError(w, r, "Failed to get room", err)
+		return
+	}
+
+	// Without the player's token the caller could be anyone
+	verified, err := s.checkPlayerToken(ctx, playerID, r.Header.Get(playerTokenHeader))
+	if err != nil {
+		serverError(w, r, "Failed to check player token", err)
+		return
+	}
+	if !verified {
+		playerID = ""
+	}
+
+	events, unsubscribe := s.events.Subscribe(roomID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// ResponseController finds the Flusher under the metrics wrapper
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		logger(ctx).Error("event stream can't be flushed", "err", err)
+		return
+	}
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.events.closed:
+			return
+		case event, ok := <-events:
+			if !ok {
+				logger(ctx).Warn("event stream fell behind and was dropped")
+				return
+			}
+			if event.Type == eventRoleAssigned && event.PlayerID != playerID {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger(ctx).Error("failed to encode event", "err", err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+
+		// Fails once the client has gone
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) getReplay(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	gameID := vars["id"]
+	if !isUUID(gameID) {
+		httpError(w, r, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get game details
+	var roomID, roomCode string
+	var winner sql.NullString
+	var startedAt time.Time
+	var endedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT g.room_id, gr.room_code, g.winner, g.started_at, g.ended_at
+		FROM games g
+		JOIN game_rooms gr ON g.room_id = gr.id
+		WHERE g.id = $1`,
+		gameID).Scan(&roomID, &roomCode, &winner, &startedAt, &endedAt)
+
+	if err == sql.ErrNoRows {
+		httpError(w, r, "Game not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		serverError(w, r, "Failed to get game", err)
+		return
+	}
+
+	// The log reveals every role, so only finished games can be replayed
+	if !endedAt.Valid {
+		httpError(w, r, "Game is still in progress", http.StatusConflict)
+		return
+	}
+
+	// Events from this game, plus the lobby events (joins) that led up to
+	// it: those since the room's previous game ended
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.id, e.event_type, e.player_id, p.username, e.target_id, e.data, e.created_at
+		FROM game_events e
+		LEFT JOIN players p ON e.player_id = p.id
+		WHERE e.game_id = $1
+		   OR (e.room_id = $2 AND e.game_id IS NULL AND e.created_at <= $3
+		       AND e.created_at > COALESCE(
+		           (SELECT MAX(ended_at) FROM games WHERE room_id = $2 AND started_at < $3),
+		           '-infinity'))
+		ORDER BY e.id ASC`,
+		gameID, roomID, startedAt)
+
+	if err != nil {
+		serverError(w, r, "Failed to get events", err)
+		return
+	}
+	defer rows.Close()
+
+	events := make([]api.GameEvent, 0)
+	for rows.Next() {
+		var event api.GameEvent
+		var playerID, username, targetID sql.NullString
+		var data []byte
+
+		err := rows.Scan(&event.Seq, &event.Type, &playerID, &username, &targetID, &data, &event.CreatedAt)
+		if err != nil {
+			logger(ctx).Warn("skipping unreadable event", "err", err)
+			continue
+		}
+
+		event.PlayerID = playerID.String
+		event.Username = username.String
+		event.TargetID = targetID.String
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &event.Data); err != nil {
+				logger(ctx).Warn("event data isn't valid JSON", "seq", event.Seq, "err", err)
+			}
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, r, "Failed to get events", err)
+		return
+	}
+
+	response := api.Replay{
+		GameID:    gameID,
+		RoomCode:  roomCode,
+		Winner:    winner.String,
+		StartedAt: startedAt,
+		EndedAt:   endedAt.Time,
+		Events:    events,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"crewmate-crisis/api"
+)
+
+func TestEventHubTaskEvents(t *testing.T) {
+	hub := newEventHub()
+	events, unsubscribe := hub.Subscribe("room1")
+	defer unsubscribe()
+	other, unsubscribeOther := hub.Subscribe("room2")
+	defer unsubscribeOther()
+
+	// Each task a player completes is its own event, not one per player
+	tasks := []string{"task1", "task2", "task5"}
+	for i, task := range tasks {
+		hub.Publish("room1", api.GameEvent{
+			Seq:      int64(i + 1),
+			Type:     eventTaskCompleted,
+			PlayerID: "p1",
+			Data:     map[string]interface{}{"task_id": task},
+		})
+	}
+
+	for i, task := range tasks {
+		select {
+		case event := <-events:
+			if event.Seq != int64(i+1) || event.Type != eventTaskCompleted || event.Data["task_id"] != task {
+				t.Errorf("event %d = %+v, want %s", i, event, task)
+			}
+		default:
+			t.Fatalf("got %d of %d task events", i, len(tasks))
+		}
+	}
+
+	select {
+	case event := <-other:
+		t.Errorf("another room got %+v", event)
+	default:
+	}
+}
+
+func TestEventHubDropsSlowSubscribers(t *testing.T) {
+	hub := newEventHub()
+	events, unsubscribe := hub.Subscribe("room1")
+	defer unsubscribe()
+
+	for i := 0; i <= eventBuffer; i++ {
+		hub.Publish("room1", api.GameEvent{Seq: int64(i + 1), Type: eventTaskCompleted})
+	}
+
+	// The buffered events are still delivered, then the channel closes
+	for i := 0; i < eventBuffer; i++ {
+		if _, ok := <-events; !ok {
+			t.Fatalf("closed after %d events, want %d", i, eventBuffer)
+		}
+	}
+	if event, ok := <-events; ok {
+		t.Errorf("got %+v after the buffer filled, want the stream closed", event)
+	}
+}
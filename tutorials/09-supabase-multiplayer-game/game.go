@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Each crewmate has this many tasks in their task list (see index.html
+// and TaskRequest)
+const tasksPerPlayer = 5
+
+// Game winners stored in games.winner
+const (
+	winnerCrewmates = "crewmates"
+	winnerImpostor  = "impostor"
+	winnerNobody    = "nobody" // ended early from the admin dashboard
+)
+
+// Game is a single round of play in a room. A room can host several
+// games when the host starts a rematch after a game finishes.
+type Game struct {
+	ID         string
+	RoomID     string
+	ImpostorID string
+	StartedAt  time.Time
+	Round      int // the meeting open now, or held last; 0 before the first
+}
+
+// currentGame returns the game that is in progress (or most recently
+// started) in a room.
+func (s *Server) currentGame(ctx context.Context, roomID string) (*Game, error) {
+	var game Game
+	err := s.db.QueryRowContext(ctx, `
+		SELECT g.id, g.room_id, g.impostor_id, g.started_at, g.meeting_round
+		FROM game_rooms gr
+		JOIN games g ON g.id = gr.current_game_id
+		WHERE gr.id = $1`,
+		roomID).Scan(&game.ID, &game.RoomID, &game.ImpostorID, &game.StartedAt, &game.Round)
+
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// tallyVotes counts the votes for a round once every living player has
+// voted. The player with the most votes is ejected, unless skip votes
+// win or there is a tie. It returns the ejected player ID (empty if
+// nobody was ejected) and whether voting for the round is complete.
+func (s *Server) tallyVotes(ctx context.Context, game *Game, round int) (string, bool, error) {
+	var alivePlayers int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM room_players WHERE room_id = $1 AND is_alive = true`,
+		game.RoomID).Scan(&alivePlayers)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT suspect_id FROM votes
+		WHERE game_id = $1 AND round = $2`,
+		game.ID, round)
+
+	if err != nil {
+		return "", false, err
+	}
+	defer rows.Close()
+
+	voteCount := make(map[string]int)
+	totalVotes := 0
+	skipVotes := 0
+	for rows.Next() {
+		var suspectID sql.NullString
+		if err := rows.Scan(&suspectID); err != nil {
+			return "", false, err
+		}
+		totalVotes++
+		if suspectID.Valid {
+			voteCount[suspectID.String]++
+		} else {
+			skipVotes++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", false, err
+	}
+
+	if totalVotes < alivePlayers {
+		return "", false, nil
+	}
+
+	// Find the player with the most votes; ties and skip votes eject nobody
+	ejectedID := ""
+	maxVotes := skipVotes
+	for suspectID, count := range voteCount {
+		if count > maxVotes {
+			maxVotes = count
+			ejectedID = suspectID
+		} else if count == maxVotes {
+			ejectedID = ""
+		}
+	}
+
+	if ejectedID == "" {
+		return "", true, nil
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE room_players SET is_alive = false
+		WHERE room_id = $1 AND player_id = $2`,
+		game.RoomID, ejectedID)
+
+	if err != nil {
+		return "", true, err
+	}
+
+	return ejectedID, true, nil
+}
+
+// checkWinner decides whether the game is over. Crewmates win when the
+// impostor is dead or every crewmate task is done; the impostor wins
+// once they are no longer outnumbered by living crewmates. Tasks are
+// counted from the game's task_completions, which only the server writes.
+func (s *Server) checkWinner(ctx context.Context, game *Game) (string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT rp.player_id, rp.is_alive,
+			(SELECT COUNT(*) FROM task_completions t
+			 WHERE t.game_id = $2 AND t.player_id = rp.player_id)
+		FROM room_players rp
+		WHERE rp.room_id = $1`,
+		game.RoomID, game.ID)
+
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	impostorAlive := false
+	aliveCrewmates := 0
+	crewmates := 0
+	tasksCompleted := 0
+	for rows.Next() {
+		var playerID string
+		var isAlive bool
+		var tasks int
+		if err := rows.Scan(&playerID, &isAlive, &tasks); err != nil {
+			return "", err
+		}
+
+		if playerID == game.ImpostorID {
+			impostorAlive = isAlive
+			continue
+		}
+
+		crewmates++
+		tasksCompleted += tasks
+		if isAlive {
+			aliveCrewmates++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	switch {
+	case !impostorAlive:
+		return winnerCrewmates, nil
+	case crewmates > 0 && tasksCompleted >= crewmates*tasksPerPlayer:
+		return winnerCrewmates, nil
+	case aliveCrewmates <= 1:
+		return winnerImpostor, nil
+	}
+	return "", nil
+}
+
+// endGame records the winner, finishes the room and awards scoreboard
+// points. Ending a game that has already ended is a no-op, and games
+// stopped by the teacher are not scored.
+func (s *Server) endGame(ctx context.Context, game *Game, winner string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE games SET winner = $1, ended_at = NOW()
+		WHERE id = $2 AND ended_at IS NULL`,
+		winner, game.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE game_rooms SET status = $1, meeting_started_at = NULL WHERE id = $2`,
+		"finished", game.RoomID)
+
+	if err != nil {
+		return err
+	}
+
+	if winner != winnerNobody {
+		if err := s.awardPoints(ctx, game, winner); err != nil {
+			return err
+		}
+	}
+
+	s.recordEvent(ctx, game.RoomID, game.ID, eventGameEnded, "", "", map[string]interface{}{
+		"winner":      winner,
+		"impostor_id": game.ImpostorID,
+	})
+
+	logger(ctx).Info("game finished", "game_id", game.ID, "room_id", game.RoomID, "winner", winner)
+	return nil
+}
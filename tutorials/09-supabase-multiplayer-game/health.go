@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"crewmate-crisis/api"
+)
+
+// readinessTimeout is how long the database has to answer a health check
+const readinessTimeout = 2 * time.Second
+
+// abandonedRoomAge is how long after a room was created, or its last game
+// started, it stops counting as active. Rooms left in the lobby never
+// finish, so without this they'd be counted forever.
+const abandonedRoomAge = 3 * time.Hour
+
+// checkDatabase pings the database and reports how long it took. The
+// endpoints are public, so the driver's error is logged rather than
+// returned.
+func (s *Server) checkDatabase(ctx context.Context) api.DatabaseHealth {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.db.PingContext(ctx)
+	health := api.DatabaseHealth{
+		Status:    "ok",
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		health.Status = "unavailable"
+		health.Error = "database unavailable"
+		logger(ctx).Warn("database health check failed", "err", err)
+	}
+	return health
+}
+
+func (s *Server) poolStats() api.PoolStats {
+	stats := s.db.Stats()
+	return api.PoolStats{
+		MaxOpen:        stats.MaxOpenConnections,
+		Open:           stats.OpenConnections,
+		InUse:          stats.InUse,
+		Idle:           stats.Idle,
+		WaitCount:      stats.WaitCount,
+		WaitDurationMs: float64(stats.WaitDuration.Microseconds()) / 1000,
+	}
+}
+
+// gameStats counts the active rooms in each phase. Players count as
+// connected while they're in an active room: one that hasn't finished
+// and isn't older than abandonedRoomAge.
+func (s *Server) gameStats(ctx context.Context) (api.GameStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	var lobby, tasks, meeting, players int
+	err := s.db.QueryRowContext(ctx, `
+		WITH active AS (
+			SELECT gr.id, gr.status, gr.meeting_started_at
+			FROM game_rooms gr
+			LEFT JOIN games g ON g.id = gr.current_game_id
+			WHERE gr.status <> 'finished'
+			  AND GREATEST(gr.created_at, g.started_at) > NOW() - make_interval(secs => $1)
+		)
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'waiting'),
+			COUNT(*) FILTER (WHERE status = 'playing' AND meeting_started_at IS NULL),
+			COUNT(*) FILTER (WHERE status = 'playing' AND meeting_started_at IS NOT NULL),
+			(SELECT COUNT(*) FROM room_players rp JOIN active a ON rp.room_id = a.id)
+		FROM active`,
+		abandonedRoomAge.Seconds()).Scan(&lobby, &tasks, &meeting, &players)
+
+	if err != nil {
+		return api.GameStats{}, err
+	}
+
+	return api.GameStats{
+		ActiveRooms: lobby + tasks + meeting,
+		RoomsByPhase: map[string]int{
+			phaseLobby:   lobby,
+			phaseTasks:   tasks,
+			phaseMeeting: meeting,
+		},
+		ConnectedPlayers: players,
+	}, nil
+}
+
+// liveness reports that the process is running and serving requests. A
+// supervisor should restart the server when this fails.
+func (s *Server) liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(api.Liveness{
+		Status:        "alive",
+		UptimeSeconds: int(time.Since(s.startedAt).Seconds()),
+	})
+}
+
+// readiness reports whether the server can handle games: 200 when the
+// database answers, 503 when it doesn't. A supervisor should hold off
+// sending players here until this passes.
+func (s *Server) readiness(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	database := s.checkDatabase(ctx)
+
+	response := api.Readiness{
+		Status:   "ready",
+		Time:     time.Now().Truncate(time.Second),
+		Database: database,
+		Pool:     s.poolStats(),
+	}
+
+	status := http.StatusOK
+	if database.Status != "ok" {
+		response.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+	} else if stats, err := s.gameStats(ctx); err == nil {
+		response.Game = &stats
+	} else {
+		logger(ctx).Warn("failed to count rooms", "err", err)
+		response.Status = "not_ready"
+		response.Error = "Failed to count rooms"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// healthCheck describes how students can reach the server. It stays 200
+// when the database is down so the lobby can still show the share URL;
+// use /api/health/ready to check the database.
+func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
+	// Every usable address, best first, with the URL students would open
+	interfaces := make([]api.InterfaceURL, 0)
+	for _, iface := range localInterfaces() {
+		interfaces = append(interfaces, api.InterfaceURL{NetworkInterface: iface, URL: s.baseURL(iface.IP)})
+	}
+
+	localIP := getLocalIP()
+	port := strconv.Itoa(s.config.Port)
+
+	mdnsURL := ""
+	if s.mdnsHost != "" {
+		mdnsURL = s.baseURL(s.mdnsHost)
+	}
+
+	status := "healthy"
+	database := s.checkDatabase(r.Context())
+	if database.Status != "ok" {
+		status = "degraded"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Health{
+		Status:     status,
+		Database:   database.Status,
+		Time:       time.Now().Truncate(time.Second),
+		LocalIP:    localIP,
+		LocalURL:   s.baseURL(localIP),
+		MDNSURL:    mdnsURL,
+		Port:       port,
+		TLSMode:    s.tlsMode,
+		Protocol:   r.Proto,
+		Interfaces: interfaces,
+	})
+}
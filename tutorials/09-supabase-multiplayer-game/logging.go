@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+)
+
+// requestIDHeader carries the request ID; an ID set by a proxy in front
+// of the server (e.g. ngrok) is kept
+const requestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	loggerKey
+)
+
+// newLogger returns a logger writing text or JSON lines at level
+func newLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// withRequestID gives every request an ID, returned in the X-Request-ID
+// header, and a logger that includes it. Requests are logged at debug
+// level; server errors are logged by serverError with their cause.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		log := slog.Default().With("request_id", id)
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		ctx = context.WithValue(ctx, loggerKey, log)
+
+		m := httpsnoop.CaptureMetrics(next, w, r.WithContext(ctx))
+		log.Debug("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", m.Code,
+			"duration", m.Duration,
+			"remote", r.RemoteAddr)
+	})
+}
+
+// withRouteFields adds the room code, game and player IDs from the URL
+// to the request's logger, so game log lines say which room they're about
+func withRouteFields(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		var attrs []any
+		if code := vars["code"]; code != "" {
+			attrs = append(attrs, "room", code)
+		}
+		if id := vars["id"]; id != "" {
+			attrs = append(attrs, "id", id)
+		}
+		if playerID := vars["playerID"]; playerID != "" {
+			attrs = append(attrs, "player", playerID)
+		}
+
+		if len(attrs) > 0 {
+			ctx := context.WithValue(r.Context(), loggerKey, logger(r.Context()).With(attrs...))
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logger returns the request's logger, or the default one outside requests
+func logger(ctx context.Context) *slog.Logger {
+	if log, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return log
+	}
+	return slog.Default()
+}
+
+// requestID returns the ID withRequestID gave the request
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
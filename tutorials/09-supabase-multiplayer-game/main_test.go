@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := redirectToHTTPS(next, "8443")
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		host     string
+		proto    string
+		location string // empty when the request is served over HTTP
+	}{
+		{"page", "GET", "/", "192.168.1.20:8080", "", "https://192.168.1.20:8443/"},
+		{"host without port", "GET", "/lobby", "crewmate.local", "", "https://crewmate.local:8443/lobby"},
+		{"IPv6 host", "GET", "/", "[fe80::1]:8080", "", "https://[fe80::1]:8443/"},
+		{"query string kept", "GET", "/api/rooms/ABCDEF/messages?after=42&limit=50", "localhost:8080", "",
+			"https://localhost:8443/api/rooms/ABCDEF/messages?after=42&limit=50"},
+		// 307 keeps the method and body, so API calls still work after the redirect
+		{"POST", "POST", "/api/rooms", "localhost:8080", "", "https://localhost:8443/api/rooms"},
+		{"PUT", "PUT", "/api/rooms/ABCDEF/settings", "localhost:8080", "", "https://localhost:8443/api/rooms/ABCDEF/settings"},
+
+		{"CA certificate", "GET", "/ca.crt", "192.168.1.20:8080", "", ""},
+		{"metrics", "GET", "/metrics", "localhost:8080", "", ""},
+		{"health", "GET", "/api/health", "localhost:8080", "", ""},
+		{"readiness", "GET", "/api/health/ready", "localhost:8080", "", ""},
+		{"already HTTPS through ngrok", "POST", "/api/rooms", "abc123.ngrok-free.app", "https", ""},
+		{"forwarded over HTTP", "GET", "/", "localhost:8080", "http", "https://localhost:8443/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			r.Host = tt.host
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if tt.location == "" {
+				if w.Code != http.StatusTeapot {
+					t.Errorf("status = %d, want the request served over HTTP", w.Code)
+				}
+				return
+			}
+			if w.Code != http.StatusTemporaryRedirect {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusTemporaryRedirect)
+			}
+			if location := w.Header().Get("Location"); location != tt.location {
+				t.Errorf("Location = %q, want %q", location, tt.location)
+			}
+		})
+	}
+}
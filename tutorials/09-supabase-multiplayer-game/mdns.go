@@ -0,0 +1,411 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"crewmate-crisis/api"
+)
+
+// mDNS (RFC 6762) and DNS-SD (RFC 6763) constants
+const (
+	mdnsPort        = 5353
+	mdnsTTL         = 120
+	mdnsServiceType = "_http._tcp.local."
+	mdnsServiceEnum = "_services._dns-sd._udp.local."
+	mdnsInstance    = "Crewmate Crisis"
+
+	dnsTypeA   = 1
+	dnsTypePTR = 12
+	dnsTypeTXT = 16
+	dnsTypeSRV = 33
+	dnsTypeANY = 255
+
+	dnsClassIN         = 1
+	dnsClassCacheFlush = 0x8000 // on unique records: replace cached ones
+	dnsClassUnicast    = 0x8000 // on questions: answer directly to the asker
+)
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: mdnsPort}
+
+// MDNSAdvertiser answers mDNS queries for <name>.local and advertises
+// the game as an HTTP service, so devices that support mDNS can open
+// http://crewmate.local:8080 instead of typing an IP address. It doesn't
+// probe for name conflicts; pick another MDNS_HOSTNAME if two servers
+// share a network.
+//
+// A socket can see queries that arrived on other interfaces, so each
+// interface answers only senders on its own subnets, with its own address.
+// Senders on none of them get every address.
+type MDNSAdvertiser struct {
+	hostname string // crewmate.local.
+	instance string // Crewmate Crisis._http._tcp.local.
+	port     int
+
+	conns []*mdnsConn
+	wg    sync.WaitGroup
+}
+
+// mdnsConn is the multicast socket for one network interface
+type mdnsConn struct {
+	*net.UDPConn
+	iface   string
+	ip      net.IP
+	subnets []*net.IPNet
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// startMDNS starts advertising name.local on port over the given
+// interfaces. Interfaces that can't join the mDNS group are skipped.
+func startMDNS(name string, port int, interfaces []api.NetworkInterface) (*MDNSAdvertiser, error) {
+	a := &MDNSAdvertiser{
+		hostname: strings.ToLower(name) + ".local.",
+		instance: mdnsInstance + "." + mdnsServiceType,
+		port:     port,
+	}
+
+	for _, ni := range interfaces {
+		iface, err := net.InterfaceByName(ni.Name)
+		if err != nil || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		// Go sets the outgoing interface for multicast to iface too
+		conn, err := net.ListenMulticastUDP("udp4", iface, mdnsGroup)
+		if err != nil {
+			slog.Warn("mDNS unavailable", "interface", ni.Name, "err", err)
+			continue
+		}
+		a.conns = append(a.conns, &mdnsConn{
+			UDPConn:  conn,
+			iface:    ni.Name,
+			ip:       net.ParseIP(ni.IP).To4(),
+			subnets:  interfaceSubnets(iface),
+			lastSent: make(map[string]time.Time),
+		})
+	}
+
+	if len(a.conns) == 0 {
+		return nil, errors.New("no network interface supports multicast")
+	}
+
+	for _, conn := range a.conns {
+		a.wg.Add(1)
+		go a.serve(conn)
+		go a.announce(conn)
+	}
+	return a, nil
+}
+
+// Hostname is the advertised name without the trailing dot
+func (a *MDNSAdvertiser) Hostname() string {
+	return strings.TrimSuffix(a.hostname, ".")
+}
+
+// Close withdraws the records and stops answering queries
+func (a *MDNSAdvertiser) Close() {
+	for _, conn := range a.conns {
+		// A TTL of zero tells caches to forget the records
+		conn.WriteToUDP(a.response([]net.IP{conn.ip}, a.allRecords(), 0), mdnsGroup)
+		conn.Close()
+	}
+	a.wg.Wait()
+}
+
+// announce sends the records unprompted, twice as RFC 6762 asks, so
+// caches on the network learn about the server right away
+func (a *MDNSAdvertiser) announce(conn *mdnsConn) {
+	for i := 0; i < 2; i++ {
+		if _, err := conn.WriteToUDP(a.response([]net.IP{conn.ip}, a.allRecords(), mdnsTTL), mdnsGroup); err != nil {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (a *MDNSAdvertiser) serve(conn *mdnsConn) {
+	defer a.wg.Done()
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Warn("mDNS stopped", "interface", conn.iface, "err", err)
+			}
+			return
+		}
+
+		questions, err := parseMDNSQuery(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		var records []mdnsRecord
+		unicast := from.Port != mdnsPort // one-shot queries want a direct answer
+		for _, q := range questions {
+			records = append(records, a.answer(q)...)
+			if q.class&dnsClassUnicast != 0 {
+				unicast = true
+			}
+		}
+		if len(records) == 0 {
+			continue
+		}
+		ips, ok := a.addressesFor(conn, from.IP)
+		if !ok {
+			continue
+		}
+
+		if unicast {
+			conn.WriteToUDP(a.response(ips, records, mdnsTTL), from)
+		} else if conn.shouldMulticast(records) {
+			conn.WriteToUDP(a.response(ips, records, mdnsTTL), mdnsGroup)
+		}
+	}
+}
+
+// addressesFor returns the addresses conn should answer sender with, or
+// false if the query belongs to another interface's socket
+func (a *MDNSAdvertiser) addressesFor(conn *mdnsConn, sender net.IP) ([]net.IP, bool) {
+	if conn.onLink(sender) {
+		return []net.IP{conn.ip}, true
+	}
+
+	var ips []net.IP
+	for _, other := range a.conns {
+		if other.onLink(sender) {
+			return nil, false
+		}
+		ips = append(ips, other.ip)
+	}
+	return ips, true
+}
+
+// onLink reports whether ip is on one of the interface's subnets
+func (c *mdnsConn) onLink(ip net.IP) bool {
+	for _, subnet := range c.subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceSubnets lists iface's IPv4 subnets
+func interfaceSubnets(iface *net.Interface) []*net.IPNet {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	var subnets []*net.IPNet
+	for _, addr := range addrs {
+		if subnet, ok := addr.(*net.IPNet); ok && subnet.IP.To4() != nil {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets
+}
+
+// shouldMulticast limits each answer to once a second per interface. On
+// some systems every socket sees queries from every interface.
+func (c *mdnsConn) shouldMulticast(records []mdnsRecord) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := ""
+	for _, r := range records {
+		key += fmt.Sprintf("%s/%d;", r.name, r.rtype)
+	}
+	if time.Since(c.lastSent[key]) < time.Second {
+		return false
+	}
+	c.lastSent[key] = time.Now()
+	return true
+}
+
+// mdnsQuestion is one question from a query
+type mdnsQuestion struct {
+	name  string
+	qtype uint16
+	class uint16
+}
+
+// mdnsRecord is a record to answer with; its data is filled in by response
+type mdnsRecord struct {
+	name  string
+	rtype uint16
+}
+
+// answer returns the records that match a question
+func (a *MDNSAdvertiser) answer(q mdnsQuestion) []mdnsRecord {
+	name := strings.ToLower(q.name)
+	matches := func(rtype uint16) bool {
+		return q.qtype == rtype || q.qtype == dnsTypeANY
+	}
+
+	switch {
+	case name == a.hostname && matches(dnsTypeA):
+		return []mdnsRecord{{a.hostname, dnsTypeA}}
+	case name == mdnsServiceEnum && matches(dnsTypePTR):
+		return []mdnsRecord{{mdnsServiceEnum, dnsTypePTR}}
+	case name == mdnsServiceType && matches(dnsTypePTR):
+		// Include everything needed to connect so clients don't have to ask again
+		return a.allRecords()
+	case name == strings.ToLower(a.instance):
+		var records []mdnsRecord
+		if matches(dnsTypeSRV) {
+			records = append(records, mdnsRecord{a.instance, dnsTypeSRV}, mdnsRecord{a.hostname, dnsTypeA})
+		}
+		if matches(dnsTypeTXT) {
+			records = append(records, mdnsRecord{a.instance, dnsTypeTXT})
+		}
+		return records
+	}
+	return nil
+}
+
+func (a *MDNSAdvertiser) allRecords() []mdnsRecord {
+	return []mdnsRecord{
+		{mdnsServiceType, dnsTypePTR},
+		{a.instance, dnsTypeSRV},
+		{a.instance, dnsTypeTXT},
+		{a.hostname, dnsTypeA},
+	}
+}
+
+// response builds an mDNS response packet with the given records, with
+// an A record for each of ips
+func (a *MDNSAdvertiser) response(ips []net.IP, records []mdnsRecord, ttl uint32) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[2:], 0x8400) // response, authoritative
+
+	answers := 0
+	add := func(r mdnsRecord, class uint16, rdata []byte) {
+		msg = append(msg, encodeDNSName(r.name)...)
+		msg = binary.BigEndian.AppendUint16(msg, r.rtype)
+		msg = binary.BigEndian.AppendUint16(msg, class)
+		msg = binary.BigEndian.AppendUint32(msg, ttl)
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(rdata)))
+		msg = append(msg, rdata...)
+		answers++
+	}
+
+	for _, r := range records {
+		var rdata []byte
+		class := uint16(dnsClassIN | dnsClassCacheFlush)
+
+		switch r.rtype {
+		case dnsTypeA:
+			for _, ip := range ips {
+				add(r, class, ip.To4())
+			}
+			continue
+		case dnsTypePTR:
+			// Shared record: other servers may answer for the same name
+			class = dnsClassIN
+			if r.name == mdnsServiceEnum {
+				rdata = encodeDNSName(mdnsServiceType)
+			} else {
+				rdata = encodeDNSName(a.instance)
+			}
+		case dnsTypeSRV:
+			rdata = make([]byte, 6)
+			binary.BigEndian.PutUint16(rdata[4:], uint16(a.port))
+			rdata = append(rdata, encodeDNSName(a.hostname)...)
+		case dnsTypeTXT:
+			rdata = []byte{byte(len("path=/"))}
+			rdata = append(rdata, "path=/"...)
+		}
+		add(r, class, rdata)
+	}
+
+	binary.BigEndian.PutUint16(msg[6:], uint16(answers))
+	return msg
+}
+
+// encodeDNSName writes a name like "crewmate.local." as DNS labels
+func encodeDNSName(name string) []byte {
+	var out []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		out = append(out, byte(len(label)))
+		out = append(out, label...)
+	}
+	return append(out, 0)
+}
+
+// parseMDNSQuery returns the questions in a query packet. Responses from
+// other hosts are ignored.
+func parseMDNSQuery(msg []byte) ([]mdnsQuestion, error) {
+	if len(msg) < 12 {
+		return nil, errors.New("short packet")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 != 0 {
+		return nil, errors.New("not a query")
+	}
+
+	count := int(binary.BigEndian.Uint16(msg[4:]))
+	questions := make([]mdnsQuestion, 0, count)
+	offset := 12
+	for i := 0; i < count; i++ {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(msg) {
+			return nil, errors.New("truncated question")
+		}
+		questions = append(questions, mdnsQuestion{
+			name:  name,
+			qtype: binary.BigEndian.Uint16(msg[next:]),
+			class: binary.BigEndian.Uint16(msg[next+2:]),
+		})
+		offset = next + 4
+	}
+	return questions, nil
+}
+
+// readDNSName reads a possibly compressed name at offset and returns it
+// with the offset just past it
+func readDNSName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, errors.New("name out of bounds")
+		}
+		length := int(msg[offset])
+
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xc0 == 0xc0:
+			// Compression pointer to an earlier name
+			if offset+1 >= len(msg) || jumps > 10 {
+				return "", 0, errors.New("bad name pointer")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3fff)
+			jumps++
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errors.New("label out of bounds")
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
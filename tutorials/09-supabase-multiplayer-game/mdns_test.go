@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"slices"
+	"testing"
+)
+
+func newTestAdvertiser() *MDNSAdvertiser {
+	return &MDNSAdvertiser{
+		hostname: "crewmate.local.",
+		instance: mdnsInstance + "." + mdnsServiceType,
+		port:     8080,
+	}
+}
+
+// testQuery builds a query packet; names may be raw, already encoded bytes
+func testQuery(questions ...[]byte) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[4:], uint16(len(questions)))
+	for _, q := range questions {
+		msg = append(msg, q...)
+	}
+	return msg
+}
+
+func testQuestion(name []byte, qtype, class uint16) []byte {
+	q := slices.Clone(name)
+	q = binary.BigEndian.AppendUint16(q, qtype)
+	return binary.BigEndian.AppendUint16(q, class)
+}
+
+// testAnswer is a record read back from a response
+type testAnswer struct {
+	name  string
+	rtype uint16
+	class uint16
+	ttl   uint32
+	rdata []byte
+}
+
+func parseTestResponse(t *testing.T, msg []byte) []testAnswer {
+	t.Helper()
+
+	if flags := binary.BigEndian.Uint16(msg[2:]); flags != 0x8400 {
+		t.Fatalf("flags = %#x, want an authoritative response", flags)
+	}
+	var answers []testAnswer
+	offset := 12
+	for i := 0; i < int(binary.BigEndian.Uint16(msg[6:])); i++ {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil {
+			t.Fatalf("answer %d: %v", i, err)
+		}
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		answers = append(answers, testAnswer{
+			name:  name,
+			rtype: binary.BigEndian.Uint16(msg[next:]),
+			class: binary.BigEndian.Uint16(msg[next+2:]),
+			ttl:   binary.BigEndian.Uint32(msg[next+4:]),
+			rdata: msg[next+10 : next+10+length],
+		})
+		offset = next + 10 + length
+	}
+	if offset != len(msg) {
+		t.Fatalf("%d bytes after the answers", len(msg)-offset)
+	}
+	return answers
+}
+
+func TestDNSNameRoundTrip(t *testing.T) {
+	for _, name := range []string{
+		"crewmate.local.",
+		mdnsServiceType,
+		mdnsServiceEnum,
+		mdnsInstance + "." + mdnsServiceType,
+	} {
+		encoded := encodeDNSName(name)
+		got, next, err := readDNSName(encoded, 0)
+		if err != nil || got != name || next != len(encoded) {
+			t.Errorf("readDNSName(encodeDNSName(%q)) = %q, %d, %v; want it back and %d", name, got, next, err, len(encoded))
+		}
+	}
+
+	if got := encodeDNSName("crewmate.local"); !slices.Equal(got, encodeDNSName("crewmate.local.")) {
+		t.Errorf("encodeDNSName without the trailing dot = %q", got)
+	}
+}
+
+func TestReadDNSNameCompression(t *testing.T) {
+	// _http._tcp.local. at 12, then a name pointing into it at "local"
+	msg := make([]byte, 12)
+	msg = append(msg, encodeDNSName(mdnsServiceType)...)
+	local := 12 + len("\x05_http\x04_tcp")
+	start := len(msg)
+	msg = append(msg, 8)
+	msg = append(msg, "crewmate"...)
+	msg = append(msg, 0xc0, byte(local))
+	msg = append(msg, 0xff) // whatever follows the name
+
+	name, next, err := readDNSName(msg, start)
+	if err != nil || name != "crewmate.local." || next != len(msg)-1 {
+		t.Errorf("readDNSName = %q, %d, %v; want crewmate.local., %d", name, next, err, len(msg)-1)
+	}
+
+	// A name that is only a pointer
+	msg = append(msg[:len(msg)-1], 0xc0, 12)
+	name, next, err = readDNSName(msg, len(msg)-2)
+	if err != nil || name != mdnsServiceType || next != len(msg) {
+		t.Errorf("readDNSName(pointer) = %q, %d, %v; want %s, %d", name, next, err, mdnsServiceType, len(msg))
+	}
+}
+
+func TestReadDNSNameMalformed(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":               {},
+		"label past the end":  {5, 'l', 'o', 'c'},
+		"no terminator":       {5, 'l', 'o', 'c', 'a', 'l'},
+		"truncated pointer":   {5, 'l', 'o', 'c', 'a', 'l', 0xc0},
+		"pointer past end":    {0xc0, 0x40},
+		"pointer to itself":   {0xc0, 0x00},
+		"pointer loop":        {1, 'a', 0xc0, 0x04, 1, 'b', 0xc0, 0x00},
+		"label after pointer": {1, 'a', 0xc0, 0x10},
+	}
+	for name, msg := range tests {
+		if got, _, err := readDNSName(msg, 0); err == nil {
+			t.Errorf("%s: readDNSName = %q, want an error", name, got)
+		}
+	}
+}
+
+func TestParseMDNSQuery(t *testing.T) {
+	// The second question points back at the first one's name
+	first := encodeDNSName("crewmate.local.")
+	msg := testQuery(
+		testQuestion(first, dnsTypeA, dnsClassIN|dnsClassUnicast),
+		testQuestion([]byte{0xc0, 12}, dnsTypeANY, dnsClassIN),
+	)
+
+	questions, err := parseMDNSQuery(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mdnsQuestion{
+		{"crewmate.local.", dnsTypeA, dnsClassIN | dnsClassUnicast},
+		{"crewmate.local.", dnsTypeANY, dnsClassIN},
+	}
+	if !slices.Equal(questions, want) {
+		t.Errorf("questions = %+v, want %+v", questions, want)
+	}
+
+	response := slices.Clone(msg)
+	binary.BigEndian.PutUint16(response[2:], 0x8400)
+	truncated := testQuery(testQuestion(first, dnsTypeA, dnsClassIN))
+	truncated = truncated[:len(truncated)-1]
+	missing := testQuery(testQuestion(first, dnsTypeA, dnsClassIN))
+	binary.BigEndian.PutUint16(missing[4:], 2)
+
+	for name, msg := range map[string][]byte{
+		"short header":       msg[:11],
+		"response":           response,
+		"truncated question": truncated,
+		"missing question":   missing,
+		"bad name":           testQuery([]byte{0xc0}),
+	} {
+		if _, err := parseMDNSQuery(msg); err == nil {
+			t.Errorf("%s: parseMDNSQuery succeeded", name)
+		}
+	}
+}
+
+func TestMDNSAnswer(t *testing.T) {
+	a := newTestAdvertiser()
+
+	tests := []struct {
+		question mdnsQuestion
+		want     []mdnsRecord
+	}{
+		{mdnsQuestion{"Crewmate.Local.", dnsTypeA, dnsClassIN}, []mdnsRecord{{a.hostname, dnsTypeA}}},
+		{mdnsQuestion{"crewmate.local.", dnsTypeANY, dnsClassIN}, []mdnsRecord{{a.hostname, dnsTypeA}}},
+		{mdnsQuestion{"crewmate.local.", dnsTypeTXT, dnsClassIN}, nil},
+		{mdnsQuestion{"other.local.", dnsTypeA, dnsClassIN}, nil},
+		{mdnsQuestion{mdnsServiceEnum, dnsTypePTR, dnsClassIN}, []mdnsRecord{{mdnsServiceEnum, dnsTypePTR}}},
+		{mdnsQuestion{mdnsServiceType, dnsTypePTR, dnsClassIN}, a.allRecords()},
+		{mdnsQuestion{a.instance, dnsTypeSRV, dnsClassIN}, []mdnsRecord{{a.instance, dnsTypeSRV}, {a.hostname, dnsTypeA}}},
+		{mdnsQuestion{"crewmate crisis._http._tcp.local.", dnsTypeTXT, dnsClassIN}, []mdnsRecord{{a.instance, dnsTypeTXT}}},
+		{mdnsQuestion{a.instance, dnsTypeANY, dnsClassIN}, []mdnsRecord{{a.instance, dnsTypeSRV}, {a.hostname, dnsTypeA}, {a.instance, dnsTypeTXT}}},
+	}
+	for _, tt := range tests {
+		if got := a.answer(tt.question); !slices.Equal(got, tt.want) {
+			t.Errorf("answer(%+v) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestMDNSResponse(t *testing.T) {
+	a := newTestAdvertiser()
+	ips := []net.IP{net.IPv4(192, 168, 1, 20), net.IPv4(10, 0, 0, 5)}
+
+	answers := parseTestResponse(t, a.response(ips, a.allRecords(), mdnsTTL))
+	if len(answers) != 5 {
+		t.Fatalf("got %d answers, want PTR, SRV, TXT and two A records", len(answers))
+	}
+
+	ptr, srv, txt := answers[0], answers[1], answers[2]
+	if ptr.name != mdnsServiceType || ptr.rtype != dnsTypePTR || ptr.class != dnsClassIN {
+		t.Errorf("PTR = %+v", ptr)
+	}
+	if target, _, err := readDNSName(ptr.rdata, 0); err != nil || target != a.instance {
+		t.Errorf("PTR target = %q, %v", target, err)
+	}
+	if srv.rtype != dnsTypeSRV || binary.BigEndian.Uint16(srv.rdata[4:]) != 8080 {
+		t.Errorf("SRV = %+v, want port 8080", srv)
+	}
+	if target, _, err := readDNSName(srv.rdata, 6); err != nil || target != a.hostname {
+		t.Errorf("SRV target = %q, %v", target, err)
+	}
+	if txt.rtype != dnsTypeTXT || string(txt.rdata) != "\x06path=/" {
+		t.Errorf("TXT = %+v", txt)
+	}
+
+	for i, answer := range answers[3:] {
+		if answer.name != a.hostname || answer.rtype != dnsTypeA || !net.IP(answer.rdata).Equal(ips[i]) {
+			t.Errorf("A record %d = %s %v, want %s", i, answer.name, net.IP(answer.rdata), ips[i])
+		}
+	}
+	for _, answer := range answers {
+		if answer.ttl != mdnsTTL {
+			t.Errorf("%s TTL = %d, want %d", answer.name, answer.ttl, mdnsTTL)
+		}
+		if answer.rtype != dnsTypePTR && answer.class != dnsClassIN|dnsClassCacheFlush {
+			t.Errorf("%s class = %#x, want cache flush", answer.name, answer.class)
+		}
+	}
+
+	// Goodbye packets carry a TTL of zero
+	for _, answer := range parseTestResponse(t, a.response(ips[:1], a.allRecords(), 0)) {
+		if answer.ttl != 0 {
+			t.Errorf("goodbye %s TTL = %d", answer.name, answer.ttl)
+		}
+	}
+}
+
+func TestMDNSAddressesFor(t *testing.T) {
+	subnet := func(cidr string) []*net.IPNet {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		return []*net.IPNet{ipNet}
+	}
+	wifi := &mdnsConn{iface: "wlan0", ip: net.IPv4(192, 168, 1, 20).To4(), subnets: subnet("192.168.1.0/24")}
+	wired := &mdnsConn{iface: "eth0", ip: net.IPv4(10, 0, 0, 5).To4(), subnets: subnet("10.0.0.0/8")}
+	a := newTestAdvertiser()
+	a.conns = []*mdnsConn{wifi, wired}
+
+	tests := []struct {
+		conn   *mdnsConn
+		sender string
+		want   []net.IP
+		ok     bool
+	}{
+		{wifi, "192.168.1.31", []net.IP{wifi.ip}, true},
+		{wired, "10.1.2.3", []net.IP{wired.ip}, true},
+		// Queries from the other interface's network are left to its socket
+		{wifi, "10.1.2.3", nil, false},
+		{wired, "192.168.1.31", nil, false},
+		// Senders on neither network get both addresses
+		{wifi, "172.16.0.9", []net.IP{wifi.ip, wired.ip}, true},
+	}
+	for _, tt := range tests {
+		got, ok := a.addressesFor(tt.conn, net.ParseIP(tt.sender))
+		if ok != tt.ok || !slices.EqualFunc(got, tt.want, net.IP.Equal) {
+			t.Errorf("%s answering %s: got %v, %v; want %v, %v", tt.conn.iface, tt.sender, got, ok, tt.want, tt.ok)
+		}
+	}
+}
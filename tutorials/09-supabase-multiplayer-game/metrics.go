@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of requests to the Supabase proxy
+const (
+	proxyForwarded   = "forwarded"
+	proxyDenied      = "denied"
+	proxyTooLarge    = "too_large"
+	proxyUnavailable = "unavailable"
+)
+
+// Metrics served at /metrics. Rooms and players are read from the
+// database when Prometheus scrapes (see gameCollector).
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crewmate_http_requests_total",
+		Help: "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "crewmate_http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	votesCast = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "crewmate_votes_cast_total",
+		Help: "Votes cast in meetings, including skip votes.",
+	})
+
+	messagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crewmate_messages_sent_total",
+		Help: "Chat messages sent by channel.",
+	}, []string{"channel"})
+
+	proxyRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crewmate_proxy_requests_total",
+		Help: "Requests to the Supabase proxy by outcome.",
+	}, []string{"outcome"})
+
+	dbErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crewmate_db_errors_total",
+		Help: "Errors returned by the database by operation.",
+	}, []string{"operation"})
+)
+
+// instrument counts requests and measures how long they take. Requests
+// are labelled with the route template (e.g. /api/rooms/{code}) rather
+// than the path, so each room doesn't get its own series.
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		// httpsnoop keeps the Hijacker the proxy needs for WebSockets
+		m := httpsnoop.CaptureMetrics(next, w, r)
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(m.Code)).Inc()
+		httpDuration.WithLabelValues(route, r.Method).Observe(m.Duration.Seconds())
+	})
+}
+
+// gameCollector reports active rooms and players at scrape time
+type gameCollector struct {
+	server  *Server
+	rooms   *prometheus.Desc
+	players *prometheus.Desc
+}
+
+func newGameCollector(s *Server) *gameCollector {
+	return &gameCollector{
+		server: s,
+		rooms: prometheus.NewDesc("crewmate_active_rooms",
+			"Active rooms (not finished or abandoned), by phase.", []string{"phase"}, nil),
+		players: prometheus.NewDesc("crewmate_connected_players",
+			"Players in active rooms.", nil, nil),
+	}
+}
+
+func (c *gameCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.rooms
+	ch <- c.players
+}
+
+func (c *gameCollector) Collect(ch chan<- prometheus.Metric) {
+	// When the database is down the gauges are left out; the failure
+	// shows up in crewmate_db_errors_total
+	stats, err := c.server.gameStats(context.Background())
+	if err != nil {
+		return
+	}
+
+	for phase, count := range stats.RoomsByPhase {
+		ch <- prometheus.MustNewConstMetric(c.rooms, prometheus.GaugeValue, float64(count), phase)
+	}
+	ch <- prometheus.MustNewConstMetric(c.players, prometheus.GaugeValue, float64(stats.ConnectedPlayers))
+}
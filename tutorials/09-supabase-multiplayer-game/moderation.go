@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"crewmate-crisis/api"
+)
+
+// Moderation actions written to moderation_log
+const (
+	moderationMasked      = "masked"
+	moderationTooLong     = "too_long"
+	moderationRateLimited = "rate_limited"
+)
+
+// defaultBlockedWords is a starting point for middle-school classrooms.
+// Teachers add their own words with CHAT_BLOCKED_WORDS.
+var defaultBlockedWords = []string{
+	"idiot", "stupid", "dumb", "loser", "shut up", "hate you", "kill yourself",
+}
+
+// Moderator checks chat messages before they are saved: it enforces a
+// maximum length, limits how fast each player can send messages and
+// masks blocked words.
+type Moderator struct {
+	maxLength  int
+	filter     *regexp.Regexp
+	rateLimit  int
+	ratePeriod time.Duration
+
+	mu        sync.Mutex
+	recent    map[string][]time.Time // messages sent in the last ratePeriod, by player
+	reported  map[string]time.Time   // when each player and action was last logged
+	lastSweep time.Time
+}
+
+// NewModerator creates a moderator. A player may send at most rateLimit
+// messages in any ratePeriod; a rateLimit of 0 disables rate limiting.
+func NewModerator(maxLength int, blockedWords []string, rateLimit int, ratePeriod time.Duration) *Moderator {
+	m := &Moderator{
+		maxLength:  maxLength,
+		rateLimit:  rateLimit,
+		ratePeriod: ratePeriod,
+		recent:     make(map[string][]time.Time),
+		reported:   make(map[string]time.Time),
+	}
+
+	var patterns []string
+	for _, word := range blockedWords {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			continue
+		}
+		// Match whole words and allow any run of spaces between phrase words
+		quoted := regexp.QuoteMeta(strings.ToLower(word))
+		patterns = append(patterns, strings.ReplaceAll(quoted, " ", `\s+`))
+	}
+	if len(patterns) > 0 {
+		m.filter = regexp.MustCompile(`(?i)\b(` + strings.Join(patterns, "|") + `)\b`)
+	}
+
+	return m
+}
+
+// TooLong reports whether a message is over the length limit
+func (m *Moderator) TooLong(content string) bool {
+	return len([]rune(content)) > m.maxLength
+}
+
+// Allow records a message from a player and reports whether it is within
+// the rate limit. Messages that are turned away don't count.
+func (m *Moderator) Allow(playerID string, now time.Time) bool {
+	if m.rateLimit <= 0 {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	// Forget messages that are outside the window
+	cutoff := now.Add(-m.ratePeriod)
+	recent := m.recent[playerID][:0]
+	for _, sent := range m.recent[playerID] {
+		if sent.After(cutoff) {
+			recent = append(recent, sent)
+		}
+	}
+
+	if len(recent) >= m.rateLimit {
+		m.recent[playerID] = recent
+		return false
+	}
+
+	m.recent[playerID] = append(recent, now)
+	return true
+}
+
+// Report reports whether a rejected message should be written to the
+// moderation log. Only a player's first rejection for each action in a
+// rate period is, so a client retrying in a loop can't flood the log.
+func (m *Moderator) Report(playerID, action string, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	key := playerID + " " + action
+	if last, ok := m.reported[key]; ok && now.Sub(last) < m.ratePeriod {
+		return false
+	}
+	m.reported[key] = now
+	return true
+}
+
+// sweep forgets players who haven't sent anything for a rate period, at
+// most once a period so it doesn't scan on every message; m.mu must be
+// held
+func (m *Moderator) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.ratePeriod {
+		return
+	}
+	m.lastSweep = now
+
+	cutoff := now.Add(-m.ratePeriod)
+	for playerID, sent := range m.recent {
+		if len(sent) == 0 || !sent[len(sent)-1].After(cutoff) {
+			delete(m.recent, playerID)
+		}
+	}
+	for key, last := range m.reported {
+		if !last.After(cutoff) {
+			delete(m.reported, key)
+		}
+	}
+}
+
+// Mask replaces blocked words with asterisks and reports whether any
+// words were masked.
+func (m *Moderator) Mask(content string) (string, bool) {
+	if m.filter == nil {
+		return content, false
+	}
+
+	masked := m.filter.ReplaceAllStringFunc(content, func(match string) string {
+		return strings.Map(func(r rune) rune {
+			if r == ' ' {
+				return r
+			}
+			return '*'
+		}, match)
+	})
+	return masked, masked != content
+}
+
+// logModeration saves a moderation action for the teacher to review
+func (s *Server) logModeration(ctx context.Context, roomID, playerID, action, content string) {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO moderation_log (room_id, player_id, action, content)
+		VALUES ($1, $2, $3, $4)`,
+		roomID, nullString(playerID), action, content)
+
+	if err != nil {
+		logger(ctx).Error("failed to log moderation action", "action", action, "player", playerID, "err", err)
+	}
+}
+
+func (s *Server) adminModerationLog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	// Optionally filter by room
+	roomCode := r.URL.Query().Get("room")
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ml.id, gr.room_code, ml.player_id, COALESCE(p.username, ''), ml.action, ml.content, ml.created_at
+		FROM moderation_log ml
+		JOIN game_rooms gr ON ml.room_id = gr.id
+		LEFT JOIN players p ON ml.player_id = p.id
+		WHERE $1 = '' OR gr.room_code = $1
+		ORDER BY ml.created_at DESC
+		LIMIT 500`,
+		roomCode)
+
+	if err != nil {
+		serverError(w, r, "Failed to get moderation log", err)
+		return
+	}
+	defer rows.Close()
+
+	entries := make([]api.ModerationEntry, 0)
+	for rows.Next() {
+		var entry api.ModerationEntry
+		var playerID sql.NullString
+		err := rows.Scan(&entry.ID, &entry.RoomCode, &playerID, &entry.Username, &entry.Action, &entry.Content, &entry.CreatedAt)
+		if err != nil {
+			continue
+		}
+		entry.PlayerID = playerID.String
+		entries = append(entries, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModeratorMask(t *testing.T) {
+	m := NewModerator(200, []string{"stupid", "shut up", " ", "a.b"}, 0, time.Second)
+
+	tests := []struct {
+		content string
+		want    string
+		masked  bool
+	}{
+		{"good game", "good game", false},
+		{"that was stupid", "that was ******", true},
+		{"STUPID move", "****** move", true},
+		{"shut   up already", "****   ** already", true},
+		{"stupidity is a word", "stupidity is a word", false},
+		{"a.b and axb", "*** and axb", true},
+	}
+	for _, tt := range tests {
+		got, masked := m.Mask(tt.content)
+		if got != tt.want || masked != tt.masked {
+			t.Errorf("Mask(%q) = %q, %v; want %q, %v", tt.content, got, masked, tt.want, tt.masked)
+		}
+	}
+
+	if got, masked := NewModerator(200, nil, 0, time.Second).Mask("stupid"); got != "stupid" || masked {
+		t.Errorf("Mask with no blocked words = %q, %v", got, masked)
+	}
+}
+
+func TestModeratorTooLong(t *testing.T) {
+	m := NewModerator(5, nil, 0, time.Second)
+
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"", false},
+		{"hello", false},
+		{"hello!", true},
+		{"héllo", false}, // counted in characters, not bytes
+		{strings.Repeat("🚀", 5), false},
+		{strings.Repeat("🚀", 6), true},
+	}
+	for _, tt := range tests {
+		if got := m.TooLong(tt.content); got != tt.want {
+			t.Errorf("TooLong(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestModeratorAllow(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	m := NewModerator(200, nil, 2, 10*time.Second)
+
+	tests := []struct {
+		player string
+		after  time.Duration
+		want   bool
+	}{
+		{"alice", 0, true},
+		{"alice", time.Second, true},
+		{"alice", 2 * time.Second, false}, // third message in the window
+		{"bob", 2 * time.Second, true},    // limits are per player
+		{"alice", 9 * time.Second, false},
+		{"alice", 10 * time.Second, true}, // the first message has left the window
+		{"alice", 11 * time.Second, true},
+		{"alice", 12 * time.Second, false},
+	}
+	for _, tt := range tests {
+		if got := m.Allow(tt.player, start.Add(tt.after)); got != tt.want {
+			t.Errorf("Allow(%s, +%v) = %v, want %v", tt.player, tt.after, got, tt.want)
+		}
+	}
+
+	// Players who stop sending are forgotten
+	m.Allow("carol", start.Add(time.Minute))
+	if _, ok := m.recent["alice"]; ok {
+		t.Error("Allow kept a player who hasn't sent anything for a rate period")
+	}
+	if _, ok := m.recent["carol"]; !ok {
+		t.Error("Allow forgot the player who just sent a message")
+	}
+
+	unlimited := NewModerator(200, nil, 0, 10*time.Second)
+	for i := 0; i < 100; i++ {
+		if !unlimited.Allow("alice", start) {
+			t.Fatal("Allow with rate limiting disabled turned a message away")
+		}
+	}
+}
+
+func TestModeratorReport(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	m := NewModerator(200, nil, 2, 10*time.Second)
+
+	tests := []struct {
+		player string
+		action string
+		after  time.Duration
+		want   bool
+	}{
+		{"alice", moderationRateLimited, 0, true},
+		{"alice", moderationRateLimited, time.Second, false},
+		{"alice", moderationTooLong, time.Second, true}, // each action is logged once
+		{"bob", moderationRateLimited, time.Second, true},
+		{"alice", moderationRateLimited, 9 * time.Second, false},
+		{"alice", moderationRateLimited, 10 * time.Second, true},
+	}
+	for _, tt := range tests {
+		if got := m.Report(tt.player, tt.action, start.Add(tt.after)); got != tt.want {
+			t.Errorf("Report(%s, %s, +%v) = %v, want %v", tt.player, tt.action, tt.after, got, tt.want)
+		}
+	}
+}
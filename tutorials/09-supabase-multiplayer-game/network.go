@@ -0,0 +1,223 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	qrcode "github.com/skip2/go-qrcode"
+
+	"crewmate-crisis/api"
+)
+
+// Kinds of network interface, best first
+const (
+	interfaceWiFi     = "wifi"
+	interfaceEthernet = "ethernet"
+	interfaceOther    = "other"
+	interfaceVPN      = "vpn"
+	interfaceVirtual  = "virtual"
+)
+
+// Interface name prefixes for each kind. Students can't reach the
+// server through container bridges, VMs or VPNs.
+var (
+	virtualInterfacePrefixes = []string{
+		"docker", "br-", "veth", "virbr", "vmnet", "vboxnet", "bridge", "cni", "flannel",
+		"cali", "kube", "lxc", "lxd", "podman", "awdl", "llw", "anpi", "ap",
+	}
+	vpnInterfacePrefixes  = []string{"utun", "tun", "tap", "wg", "tailscale", "zt", "ppp", "ipsec"}
+	wifiInterfacePrefixes = []string{"wl", "wlan", "wi-fi", "wifi", "wireless"}
+	ethInterfacePrefixes  = []string{"eth", "en", "eno", "enp", "ens", "ethernet"}
+)
+
+// QR code sizes in pixels
+const (
+	defaultQRSize = 256
+	maxQRSize     = 1024
+)
+
+// localInterfaces lists the IPv4 addresses of the machine's interfaces
+// that are up, best first: Wi-Fi and Ethernet on a private network before
+// VPNs, container bridges and VMs.
+func localInterfaces() []api.NetworkInterface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var found []api.NetworkInterface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip := ipnet.IP.To4()
+			if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+				continue
+			}
+
+			kind := interfaceKind(iface.Name, ip)
+			found = append(found, api.NetworkInterface{
+				Name:  iface.Name,
+				IP:    ip.String(),
+				Kind:  kind,
+				Score: interfaceScore(kind, ip, iface.Flags),
+			})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Score > found[j].Score
+	})
+	return found
+}
+
+// interfaceKind guesses what an interface is from its name
+func interfaceKind(name string, ip net.IP) string {
+	lower := strings.ToLower(name)
+	hasPrefix := func(prefixes []string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(lower, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case hasPrefix(virtualInterfacePrefixes):
+		return interfaceVirtual
+	case hasPrefix(vpnInterfacePrefixes):
+		return interfaceVPN
+	case hasPrefix(wifiInterfacePrefixes):
+		return interfaceWiFi
+	case hasPrefix(ethInterfacePrefixes):
+		return interfaceEthernet
+	}
+
+	// Tailscale and other CGNAT-range overlays
+	if ip[0] == 100 && ip[1]&0xc0 == 64 {
+		return interfaceVPN
+	}
+	return interfaceOther
+}
+
+// interfaceScore ranks an address; higher is more likely to be the
+// classroom network
+func interfaceScore(kind string, ip net.IP, flags net.Flags) int {
+	score := map[string]int{
+		interfaceWiFi:     40,
+		interfaceEthernet: 30,
+		interfaceOther:    20,
+		interfaceVPN:      5,
+		interfaceVirtual:  0,
+	}[kind]
+
+	// Home and school networks use private addresses; Docker's default
+	// bridge (172.17.0.0/16) does too, so it gets no bonus
+	if ip.IsPrivate() && !(ip[0] == 172 && ip[1] == 17) {
+		score += 10
+	}
+	if flags&net.FlagBroadcast != 0 {
+		score += 5
+	}
+	return score
+}
+
+func getLocalIP() string {
+	if ifaces := localInterfaces(); len(ifaces) > 0 {
+		return ifaces[0].IP
+	}
+	return "127.0.0.1"
+}
+
+// baseURL is the address students should open, using HTTPS when enabled
+func (s *Server) baseURL(host string) string {
+	if s.tlsMode != tlsModeOff {
+		return fmt.Sprintf("https://%s", net.JoinHostPort(host, strconv.Itoa(s.config.HTTPSPort)))
+	}
+	return fmt.Sprintf("http://%s", net.JoinHostPort(host, strconv.Itoa(s.config.Port)))
+}
+
+// joinBaseURL picks the address to put in join links. When the request
+// came in on an address students can reach too (the LAN IP, a .local
+// name or ngrok) it's reused; from localhost the best interface is used.
+func (s *Server) joinBaseURL(r *http.Request) string {
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname = host
+	}
+
+	if hostname != "" && hostname != "localhost" {
+		if ip := net.ParseIP(hostname); ip == nil || !ip.IsLoopback() {
+			scheme := "http"
+			if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+				scheme = "https"
+			}
+			return scheme + "://" + host
+		}
+	}
+
+	return s.baseURL(getLocalIP())
+}
+
+// getRoomQR returns a PNG QR code that opens the game with the room code
+// filled in. ?size= sets the width in pixels.
+func (s *Server) getRoomQR(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	roomCode := vars["code"]
+
+	var roomID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id FROM game_rooms WHERE room_code = $1`,
+		roomCode).Scan(&roomID)
+
+	if err == sql.ErrNoRows {
+		apiError(w, r, api.CodeRoomNotFound, "Room not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		serverError(w, r, "Failed to get room", err)
+		return
+	}
+
+	size := defaultQRSize
+	if value := r.URL.Query().Get("size"); value != "" {
+		size, err = strconv.Atoi(value)
+		if err != nil || size < 64 || size > maxQRSize {
+			httpError(w, r, fmt.Sprintf("size must be between 64 and %d", maxQRSize), http.StatusBadRequest)
+			return
+		}
+	}
+
+	joinURL := s.joinBaseURL(r) + "/?room=" + roomCode
+	png, err := qrcode.Encode(joinURL, qrcode.Medium, size)
+	if err != nil {
+		serverError(w, r, "Failed to create QR code", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Join-URL", joinURL)
+	w.Write(png)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestInterfaceKind(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want string
+	}{
+		{"wlan0", "192.168.1.20", interfaceWiFi},
+		{"wlp2s0", "192.168.1.20", interfaceWiFi},
+		{"Wi-Fi", "192.168.1.20", interfaceWiFi},
+		{"eth0", "10.0.0.5", interfaceEthernet},
+		{"enp3s0", "10.0.0.5", interfaceEthernet},
+		{"en0", "192.168.1.20", interfaceEthernet},
+		{"Ethernet 2", "10.0.0.5", interfaceEthernet},
+		{"docker0", "172.17.0.1", interfaceVirtual},
+		{"br-1a2b3c", "172.18.0.1", interfaceVirtual},
+		{"veth12ab", "172.17.0.3", interfaceVirtual},
+		{"vboxnet0", "192.168.56.1", interfaceVirtual},
+		{"virbr0", "192.168.122.1", interfaceVirtual},
+		{"utun3", "10.8.0.2", interfaceVPN},
+		{"tun0", "10.8.0.2", interfaceVPN},
+		{"wg0", "10.6.0.2", interfaceVPN},
+		{"tailscale0", "100.101.102.103", interfaceVPN},
+		// Unknown names in Tailscale's CGNAT range are VPNs too
+		{"unknown0", "100.64.0.1", interfaceVPN},
+		{"unknown0", "100.127.255.1", interfaceVPN},
+		{"unknown0", "100.128.0.1", interfaceOther},
+		{"unknown0", "192.168.1.20", interfaceOther},
+		// localInterfaces skips loopback by flag, not by name
+		{"lo", "127.0.0.1", interfaceOther},
+	}
+	for _, tt := range tests {
+		if got := interfaceKind(tt.name, net.ParseIP(tt.ip).To4()); got != tt.want {
+			t.Errorf("interfaceKind(%q, %s) = %q, want %q", tt.name, tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestInterfaceScore(t *testing.T) {
+	lan := net.FlagUp | net.FlagBroadcast
+	tests := []struct {
+		kind  string
+		ip    string
+		flags net.Flags
+		want  int
+	}{
+		{interfaceWiFi, "192.168.1.20", lan, 55},
+		{interfaceEthernet, "10.0.0.5", lan, 45},
+		{interfaceOther, "192.168.1.20", lan, 35},
+		{interfaceWiFi, "203.0.113.5", lan, 45},
+		{interfaceVPN, "10.8.0.2", net.FlagUp | net.FlagPointToPoint, 15},
+		{interfaceVirtual, "172.18.0.1", lan, 15},
+		// Docker's default bridge gets no private address bonus
+		{interfaceVirtual, "172.17.0.1", lan, 5},
+		{interfaceOther, "127.0.0.1", net.FlagUp | net.FlagLoopback, 20},
+	}
+	for _, tt := range tests {
+		if got := interfaceScore(tt.kind, net.ParseIP(tt.ip).To4(), tt.flags); got != tt.want {
+			t.Errorf("interfaceScore(%q, %s, %v) = %d, want %d", tt.kind, tt.ip, tt.flags, got, tt.want)
+		}
+	}
+
+	// The classroom network comes before anything else on the laptop
+	wifi := interfaceScore(interfaceWiFi, net.ParseIP("192.168.1.20").To4(), lan)
+	for _, kind := range []string{interfaceEthernet, interfaceOther, interfaceVPN, interfaceVirtual} {
+		if other := interfaceScore(kind, net.ParseIP("192.168.1.21").To4(), lan); other >= wifi {
+			t.Errorf("%s scores %d, Wi-Fi %d", kind, other, wifi)
+		}
+	}
+}
@@ -31,7 +31,7 @@ echo ""
 # Check if Go server is running
 if ! curl -s http://localhost:8080 > /dev/null 2>&1; then
     echo "⚠️  Go server doesn't seem to be running!"
-    echo "   Run this first: go run main.go"
+    echo "   Run this first: go run ."
     echo ""
     exit 1
 fi
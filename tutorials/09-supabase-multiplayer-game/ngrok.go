@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ngrokTunnelsRefresh is how long the agent's tunnel list is reused
+// before asking again
+const ngrokTunnelsRefresh = 5 * time.Second
+
+// ngrokTunnels finds the public URLs of ngrok tunnels to this server by
+// asking the local ngrok agent. start-ngrok.sh starts the tunnel after
+// the server, so pages on its URL are allowed as soon as it's up without
+// allowing every ngrok domain.
+type ngrokTunnels struct {
+	api    string   // the agent's API, e.g. http://127.0.0.1:4040
+	ports  []string // tunnels to these local ports lead to this server
+	client *http.Client
+
+	mu      sync.Mutex
+	origins []string
+	fetched time.Time
+}
+
+func newNgrokTunnels(api string, ports ...string) *ngrokTunnels {
+	return &ngrokTunnels{
+		api:    strings.TrimSuffix(api, "/"),
+		ports:  ports,
+		client: &http.Client{Timeout: time.Second},
+	}
+}
+
+// Allowed reports whether origin is one of the tunnels' public URLs
+func (n *ngrokTunnels) Allowed(origin string) bool {
+	return slices.Contains(n.Origins(), origin)
+}
+
+// Origins lists the tunnels' public URLs, asking the agent at most once
+// per ngrokTunnelsRefresh. There are none while the agent isn't running.
+func (n *ngrokTunnels) Origins() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if time.Since(n.fetched) < ngrokTunnelsRefresh {
+		return n.origins
+	}
+	n.fetched = time.Now()
+
+	origins, err := n.fetch()
+	if err != nil {
+		slog.Debug("ngrok agent unavailable", "api", n.api, "err", err)
+	}
+	n.origins = origins
+	return origins
+}
+
+// fetch reads the agent's tunnel list, keeping the HTTPS tunnels to
+// this server
+func (n *ngrokTunnels) fetch() ([]string, error) {
+	resp, err := n.client.Get(n.api + "/api/tunnels")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ngrok agent: %s", resp.Status)
+	}
+
+	var list struct {
+		Tunnels []struct {
+			PublicURL string `json:"public_url"`
+			Config    struct {
+				Addr string `json:"addr"`
+			} `json:"config"`
+		} `json:"tunnels"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&list); err != nil {
+		return nil, fmt.Errorf("ngrok agent: %w", err)
+	}
+
+	var origins []string
+	for _, tunnel := range list.Tunnels {
+		if strings.HasPrefix(tunnel.PublicURL, "https://") && slices.Contains(n.ports, addrPort(tunnel.Config.Addr)) {
+			origins = append(origins, strings.TrimSuffix(tunnel.PublicURL, "/"))
+		}
+	}
+	return origins, nil
+}
+
+// addrPort is the port of a tunnel's local address, which ngrok reports
+// as 8080, localhost:8080 or http://localhost:8080
+func addrPort(addr string) string {
+	addr = strings.TrimSuffix(addr, "/")
+	if i := strings.LastIndex(addr, ":"); i >= 0 {
+		return addr[i+1:]
+	}
+	return addr
+}
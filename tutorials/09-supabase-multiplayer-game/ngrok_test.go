@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// newTestNgrokAgent serves a tunnel list like the ngrok agent's
+func newTestNgrokAgent(t *testing.T, requests *int) string {
+	t.Helper()
+
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/api/tunnels" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"tunnels": [
+			{"public_url": "https://game.ngrok-free.app", "proto": "https", "config": {"addr": "http://localhost:8080"}},
+			{"public_url": "https://secure.ngrok-free.app/", "proto": "https", "config": {"addr": "https://localhost:8443"}},
+			{"public_url": "http://game.ngrok-free.app", "proto": "http", "config": {"addr": "localhost:8080"}},
+			{"public_url": "https://supabase.ngrok-free.app", "proto": "https", "config": {"addr": "54321"}}
+		]}`))
+	}))
+	t.Cleanup(agent.Close)
+	return agent.URL
+}
+
+func TestNgrokTunnels(t *testing.T) {
+	var requests int
+	tunnels := newNgrokTunnels(newTestNgrokAgent(t, &requests)+"/", "8080", "8443")
+
+	// HTTPS tunnels to this server only, not the Supabase one
+	want := []string{"https://game.ngrok-free.app", "https://secure.ngrok-free.app"}
+	if got := tunnels.Origins(); !slices.Equal(got, want) {
+		t.Errorf("Origins = %q, want %q", got, want)
+	}
+
+	policy := &CORSPolicy{Tunnels: tunnels}
+	tests := map[string]bool{
+		"https://game.ngrok-free.app":     true,
+		"https://secure.ngrok-free.app":   true,
+		"http://game.ngrok-free.app":      false,
+		"https://supabase.ngrok-free.app": false,
+		"https://other.ngrok-free.app":    false,
+	}
+	for origin, want := range tests {
+		if got := policy.Allowed(origin); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", origin, got, want)
+		}
+	}
+
+	// The list is reused rather than fetched for every request
+	if requests != 1 {
+		t.Errorf("agent asked %d times, want once", requests)
+	}
+}
+
+func TestNgrokTunnelsAgentDown(t *testing.T) {
+	agent := httptest.NewServer(http.NotFoundHandler())
+	agent.Close()
+
+	policy := &CORSPolicy{Tunnels: newNgrokTunnels(agent.URL, "8080")}
+	if policy.Allowed("https://game.ngrok-free.app") {
+		t.Error("ngrok origin allowed with no agent running")
+	}
+}
+
+func TestAddrPort(t *testing.T) {
+	for addr, want := range map[string]string{
+		"8080":                    "8080",
+		"localhost:8080":          "8080",
+		"http://localhost:8080":   "8080",
+		"https://localhost:8443/": "8443",
+	} {
+		if got := addrPort(addr); got != want {
+			t.Errorf("addrPort(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"crewmate-crisis/api"
+)
+
+// apiRoute is an /api endpoint: main registers the handler, and the
+// OpenAPI document at /api/openapi.json is generated from the rest
+type apiRoute struct {
+	method      string
+	path        string // relative to /api
+	tag         string
+	summary     string
+	handler     http.HandlerFunc
+	admin       bool        // needs the teacher's password
+	player      bool        // needs the token of the player in the request
+	request     any         // the JSON body, nil when there isn't one
+	response    any         // the JSON body, nil when there isn't one
+	status      int         // success status when it isn't 200
+	media       string      // a non-JSON body the endpoint can return instead
+	stream      bool        // stays open, so isn't cut off by the request timeout
+	query       []parameter // query string parameters
+	header      []parameter // request headers
+	description string
+}
+
+// parameter is a path or query string parameter
+type parameter struct {
+	name        string
+	schema      map[string]any
+	description string
+}
+
+var (
+	uuidSchema     = map[string]any{"type": "string", "format": "uuid"}
+	roomCodeSchema = map[string]any{"type": "string", "pattern": "^[A-Z]{6}$"}
+	integerSchema  = map[string]any{"type": "integer"}
+)
+
+// pathParameters describes the variables used in route paths
+var pathParameters = map[string]parameter{
+	"code":     {"code", roomCodeSchema, "Room code"},
+	"id":       {"id", uuidSchema, "Game or class session ID"},
+	"playerID": {"playerID", uuidSchema, "Player ID"},
+}
+
+var pathVariable = regexp.MustCompile(`\{(\w+)\}`)
+
+// playerToken is the header that proves a request comes from player_id
+var playerToken = parameter{playerTokenHeader, map[string]any{"type": "string"},
+	"The player_token from joining; without it only public information is returned"}
+
+// apiRoutes lists every /api endpoint
+func (s *Server) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{method: "POST", path: "/rooms", tag: "rooms", summary: "Create a room and join it as the host",
+			handler: s.createRoom, request: api.CreateRoomRequest{}, response: api.JoinedRoom{}},
+		{method: "POST", path: "/rooms/join", tag: "rooms", summary: "Join a room",
+			handler: s.joinRoom, request: api.JoinRoomRequest{}, response: api.JoinedRoom{}},
+		{method: "GET", path: "/rooms/{code}", tag: "rooms", summary: "Get a room and its players",
+			handler: s.getRoom, response: api.GameRoom{},
+			query: []parameter{
+				{"player_id", uuidSchema, "The player asking, who is told the impostor_id if it's them"},
+			},
+			header: []parameter{playerToken}},
+		{method: "POST", path: "/rooms/{code}/start", tag: "games", summary: "Start a game",
+			handler: s.startGame, response: api.GameStarted{},
+			query: []parameter{
+				{"player_id", uuidSchema, "The player starting the game, who is told the impostor_id if it's them"},
+			},
+			header: []parameter{playerToken}},
+		{method: "POST", path: "/rooms/{code}/vote", tag: "games", summary: "Vote in a meeting",
+			player: true, handler: s.submitVote, request: api.VoteRequest{}, response: api.VoteResult{}},
+		{method: "POST", path: "/rooms/{code}/task", tag: "games", summary: "Complete a task",
+			player: true, handler: s.completeTask, request: api.TaskRequest{}, response: api.TaskResult{}},
+		{method: "POST", path: "/rooms/{code}/emergency", tag: "games", summary: "Call an emergency meeting",
+			player: true, handler: s.callEmergency, request: api.EmergencyRequest{}, response: api.MeetingCalled{}},
+		{method: "POST", path: "/rooms/{code}/message", tag: "chat", summary: "Send a chat or quick-chat message",
+			player: true, handler: s.sendMessage, request: api.MessageRequest{}, response: api.MessageSent{}},
+		{method: "GET", path: "/rooms/{code}/messages", tag: "chat", summary: "Read the chat",
+			handler: s.getMessages, response: []api.ChatMessage{},
+			description: "Returns the latest page, or the messages after a cursor. X-Has-More says whether there are more " +
+				"and X-Next-Cursor is the cursor for the next request.",
+			query: []parameter{
+				{"player_id", uuidSchema, "The player reading, which decides the channels returned"},
+				{"limit", integerSchema, "Page size, at most 200"},
+				{"after", uuidSchema, "Only messages after this one"},
+			},
+			header: []parameter{playerToken}},
+		{method: "GET", path: "/rooms/{code}/events", tag: "games", summary: "Stream a room's game events",
+			handler: s.streamEvents, media: "text/event-stream", stream: true,
+			description: "Sends each GameEvent as a server-sent event named after its type, with the event's seq as its id. " +
+				"The stream ends if the client falls too far behind.",
+			query: []parameter{
+				{"player_id", uuidSchema, "The player listening, who is sent their own role_assigned event"},
+			},
+			header: []parameter{playerToken}},
+		{method: "PUT", path: "/rooms/{code}/settings", tag: "rooms", summary: "Change a room's chat settings",
+			player: true, handler: s.updateRoomSettings, request: api.RoomSettingsRequest{}, response: api.RoomSettings{}},
+		{method: "GET", path: "/rooms/{code}/scoreboard", tag: "rooms", summary: "Get a room's scoreboard",
+			handler: s.getScoreboard, response: api.Scoreboard{}},
+		{method: "GET", path: "/rooms/{code}/transcript", tag: "games", summary: "Download a finished game's transcript",
+			handler: s.getTranscript, response: api.Transcript{}, media: "text/markdown",
+			query: []parameter{
+				{"game_id", uuidSchema, "The game; the most recently finished one by default"},
+				{"format", map[string]any{"type": "string", "enum": []string{"json", "markdown"}}, "json by default"},
+			}},
+		{method: "GET", path: "/rooms/{code}/qr", tag: "rooms", summary: "Get a QR code that joins the room",
+			handler: s.getRoomQR, media: "image/png",
+			query: []parameter{{"size", integerSchema, "Width in pixels"}}},
+		{method: "GET", path: "/games/{id}/replay", tag: "games", summary: "Get a finished game's event log",
+			handler: s.getReplay, response: api.Replay{}},
+		{method: "GET", path: "/quickchat", tag: "chat", summary: "List quick-chat phrases",
+			handler: s.getQuickChat, response: api.QuickChatCatalog{}},
+		{method: "GET", path: "/health", tag: "health", summary: "Show how students can reach the server",
+			handler: s.healthCheck, response: api.Health{}},
+		{method: "GET", path: "/health/live", tag: "health", summary: "Check the server is running",
+			handler: s.liveness, response: api.Liveness{}},
+		{method: "GET", path: "/health/ready", tag: "health", summary: "Check the server can reach the database",
+			handler: s.readiness, response: api.Readiness{}, description: "Responds 503 with the same body when it can't."},
+		{method: "GET", path: "/openapi.json", tag: "docs", summary: "Get this document",
+			handler: s.getOpenAPI, response: map[string]any{}},
+
+		// Teacher dashboard
+		{method: "GET", path: "/admin/rooms", tag: "admin", summary: "List rooms", admin: true,
+			handler: s.adminListRooms, response: []api.RoomSummary{}},
+		{method: "POST", path: "/admin/rooms/{code}/end", tag: "admin", summary: "End a room's game and close it", admin: true,
+			handler: s.adminEndGame, response: api.RoomEnded{}},
+		{method: "DELETE", path: "/admin/rooms/{code}/players/{playerID}", tag: "admin", summary: "Remove a player from a room", admin: true,
+			handler: s.adminKickPlayer, response: api.PlayerKicked{}},
+		{method: "DELETE", path: "/admin/rooms/{code}/messages", tag: "admin", summary: "Delete a room's chat", admin: true,
+			handler: s.adminWipeChat, response: api.ChatWiped{}},
+		{method: "GET", path: "/admin/moderation", tag: "admin", summary: "List moderated messages", admin: true,
+			handler: s.adminModerationLog, response: []api.ModerationEntry{},
+			query: []parameter{{"room", roomCodeSchema, "Only this room"}}},
+		{method: "GET", path: "/admin/sessions", tag: "admin", summary: "List class sessions", admin: true,
+			handler: s.listSessions, response: []api.SessionSummary{}},
+		{method: "POST", path: "/admin/sessions", tag: "admin", summary: "Start a class session", admin: true,
+			handler: s.createSession, request: api.CreateSessionRequest{}, response: api.ClassSession{}, status: http.StatusCreated},
+		{method: "POST", path: "/admin/sessions/{id}/end", tag: "admin", summary: "End a class session", admin: true,
+			handler: s.endSession, response: api.SessionEnded{}},
+		{method: "GET", path: "/admin/sessions/{id}/report", tag: "admin", summary: "Get a class session's report", admin: true,
+			handler: s.getSessionReport, response: api.SessionReport{}},
+	}
+}
+
+// openAPIDocument describes routes as an OpenAPI 3 document
+func openAPIDocument(routes []apiRoute) map[string]any {
+	schemas := api.Schemas{}
+	errorResponse := map[string]any{
+		"description": "Error",
+		"content":     jsonContent(schemas.For(api.ErrorResponse{})),
+	}
+
+	paths := make(map[string]map[string]any)
+	for _, route := range routes {
+		operation := map[string]any{
+			"operationId": operationID(route.handler),
+			"summary":     route.summary,
+			"tags":        []string{route.tag},
+		}
+		if route.description != "" {
+			operation["description"] = route.description
+		}
+
+		var parameters []map[string]any
+		for _, match := range pathVariable.FindAllStringSubmatch(route.path, -1) {
+			parameters = append(parameters, pathParameters[match[1]].describe("path"))
+		}
+		for _, param := range route.query {
+			parameters = append(parameters, param.describe("query"))
+		}
+		for _, param := range route.header {
+			parameters = append(parameters, param.describe("header"))
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if route.request != nil {
+			schema := schemas.For(route.request)
+			operation["requestBody"] = map[string]any{
+				"required": hasRequiredFields(schemas, schema),
+				"content":  jsonContent(schema),
+			}
+		}
+
+		status := route.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		content := map[string]any{}
+		if route.response != nil {
+			content = jsonContent(schemas.For(route.response))
+		}
+		if route.media != "" {
+			body := map[string]any{"type": "string"}
+			if !strings.HasPrefix(route.media, "text/") {
+				body["format"] = "binary"
+			}
+			content[route.media] = map[string]any{"schema": body}
+		}
+		if len(content) > 0 {
+			success["content"] = content
+		}
+		operation["responses"] = map[string]any{
+			strconv.Itoa(status): success,
+			"default":            errorResponse,
+		}
+
+		if route.admin {
+			operation["security"] = []map[string][]string{{"teacher": {}}}
+		}
+		if route.player {
+			operation["security"] = []map[string][]string{{"player": {}}}
+		}
+
+		if paths[route.path] == nil {
+			paths[route.path] = make(map[string]any)
+		}
+		paths[route.path][strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Crewmate Crisis",
+			"version":     "1.0.0",
+			"description": "The game server's API. Errors are returned as an ErrorResponse with a stable code.",
+		},
+		"servers": []map[string]string{{"url": "/api"}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"teacher": map[string]string{
+					"type":        "http",
+					"scheme":      "basic",
+					"description": "Any username, with ADMIN_PASSWORD as the password",
+				},
+				"player": map[string]string{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        playerTokenHeader,
+					"description": "The player_token from joining, for the player_id or voter_id in the request",
+				},
+			},
+		},
+	}
+}
+
+func (p parameter) describe(in string) map[string]any {
+	return map[string]any{
+		"name":        p.name,
+		"in":          in,
+		"required":    in == "path",
+		"description": p.description,
+		"schema":      p.schema,
+	}
+}
+
+// hasRequiredFields reports whether a request body must be sent; one
+// whose fields are all optional can be left out
+func hasRequiredFields(schemas api.Schemas, schema map[string]any) bool {
+	ref, _ := schema["$ref"].(string)
+	if named, ok := schemas[path.Base(ref)].(map[string]any); ok {
+		schema = named
+	}
+	_, ok := schema["required"]
+	return ok
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// operationID names an operation after its handler, e.g. createRoom
+func operationID(handler http.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func (s *Server) getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.openAPI)
+}
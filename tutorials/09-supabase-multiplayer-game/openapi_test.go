@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"crewmate-crisis/api"
+)
+
+// testOpenAPIDocument builds the document as main does and decodes it
+// the way a client would see it
+func testOpenAPIDocument(t *testing.T) map[string]any {
+	t.Helper()
+
+	encoded, err := json.Marshal(openAPIDocument((&Server{}).apiRoutes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// refs collects every $ref in v
+func refs(v any, found map[string]bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				found[ref] = true
+			}
+			refs(value, found)
+		}
+	case []any:
+		for _, value := range v {
+			refs(value, found)
+		}
+	}
+}
+
+func TestOpenAPIDocumentRefs(t *testing.T) {
+	doc := testOpenAPIDocument(t)
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+	found := make(map[string]bool)
+	refs(doc, found)
+	if len(found) == 0 {
+		t.Fatal("no $refs in the document")
+	}
+	for ref := range found {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		if !ok || schemas[name] == nil {
+			t.Errorf("$ref %s doesn't resolve", ref)
+		}
+	}
+}
+
+func TestOpenAPIDocumentOperations(t *testing.T) {
+	doc := testOpenAPIDocument(t)
+	paths := doc["paths"].(map[string]any)
+
+	operationIDs := make(map[string]string)
+	for _, route := range (&Server{}).apiRoutes() {
+		name := route.method + " " + route.path
+		operation, ok := paths[route.path].(map[string]any)[strings.ToLower(route.method)].(map[string]any)
+		if !ok {
+			t.Errorf("%s is missing", name)
+			continue
+		}
+
+		id, _ := operation["operationId"].(string)
+		if id == "" || operationIDs[id] != "" {
+			t.Errorf("%s: operationId %q is empty or also used by %s", name, id, operationIDs[id])
+		}
+		operationIDs[id] = name
+
+		// Every path variable is described and required
+		declared := make(map[string]bool)
+		params, _ := operation["parameters"].([]any)
+		for _, p := range params {
+			param := p.(map[string]any)
+			if param["in"] == "path" {
+				declared[param["name"].(string)] = param["required"] == true
+			}
+		}
+		for _, match := range pathVariable.FindAllStringSubmatch(route.path, -1) {
+			if !declared[match[1]] {
+				t.Errorf("%s: path parameter %s isn't described as required", name, match[1])
+			}
+		}
+
+		responses := operation["responses"].(map[string]any)
+		if responses["default"] == nil {
+			t.Errorf("%s has no error response", name)
+		}
+		security, secured := operation["security"].([]any)
+		if secured != (route.admin || route.player) {
+			t.Errorf("%s: security = %v, want it only on admin and player routes", name, security)
+		}
+		if route.player {
+			if _, ok := security[0].(map[string]any)["player"]; !ok {
+				t.Errorf("%s: security = %v, want the player token", name, security)
+			}
+		}
+	}
+}
+
+func TestOpenAPIDocumentRequestBodies(t *testing.T) {
+	doc := testOpenAPIDocument(t)
+	paths := doc["paths"].(map[string]any)
+
+	tests := map[string]bool{
+		"/rooms/{code}/vote":      true,
+		"/rooms/{code}/emergency": true,
+		"/admin/sessions":         true,
+	}
+	for path, want := range tests {
+		body := paths[path].(map[string]any)["post"].(map[string]any)["requestBody"].(map[string]any)
+		if body["required"] != want {
+			t.Errorf("%s requestBody required = %v, want %v", path, body["required"], want)
+		}
+	}
+
+	// A body whose fields are all optional can be left out
+	type optionalRequest struct {
+		Note string `json:"note,omitempty"`
+	}
+	optional := api.Schemas{}
+	if hasRequiredFields(optional, optional.For(optionalRequest{})) {
+		t.Error("hasRequiredFields(optionalRequest) = true, want false")
+	}
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	vote := schemas["VoteRequest"].(map[string]any)
+	required := vote["required"].([]any)
+	for _, field := range required {
+		if field == "room_id" || field == "suspect_id" {
+			t.Errorf("VoteRequest requires optional field %s", field)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"path"
+	"strings"
+	"time"
+
+	"crewmate-crisis/api"
+	"crewmate-crisis/config"
+)
+
+// proxyPrefix is the path the frontend uses for Supabase when running
+// through ngrok (see SUPABASE_URL in app.js)
+const proxyPrefix = "/supabase-proxy"
+
+// blockedProxyPaths are never proxied, whatever the allowlist says. They
+// are Supabase's admin APIs, which only the service role should reach.
+var blockedProxyPaths = []string{
+	"/auth/v1/admin",
+	"/pg",
+	"/analytics",
+	"/storage/v1/admin",
+}
+
+// CORS headers from Supabase are dropped; the server's CORSPolicy sets them
+var proxyCORSHeaders = []string{
+	"Access-Control-Allow-Origin",
+	"Access-Control-Allow-Methods",
+	"Access-Control-Allow-Headers",
+	"Access-Control-Allow-Credentials",
+	"Access-Control-Expose-Headers",
+	"Access-Control-Max-Age",
+}
+
+// ProxyPolicy decides which requests the Supabase proxy forwards
+type ProxyPolicy struct {
+	Rules        []config.ProxyRule
+	AnonKey      string // when set, requests must carry this apikey
+	MaxBodyBytes int64
+}
+
+// pathUnder reports whether p is prefix itself or a path below it
+func pathUnder(p, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// Check returns why a request (with the proxy prefix already stripped)
+// may not be forwarded, or nil if it may.
+func (p *ProxyPolicy) Check(r *http.Request) error {
+	// Refuse anything that could escape an allowed prefix
+	if r.URL.RawPath != "" || path.Clean(r.URL.Path) != r.URL.Path {
+		return errors.New("path is not canonical")
+	}
+
+	for _, blocked := range blockedProxyPaths {
+		if pathUnder(r.URL.Path, blocked) {
+			return errors.New("admin route")
+		}
+	}
+
+	allowed := false
+	for _, rule := range p.Rules {
+		if !pathUnder(r.URL.Path, rule.Prefix) {
+			continue
+		}
+		for _, method := range rule.Methods {
+			if r.Method == method {
+				allowed = true
+			}
+		}
+	}
+	if !allowed {
+		return errors.New("not in allowlist")
+	}
+
+	// The Realtime WebSocket sends its key as a query parameter
+	apiKey := r.Header.Get("apikey")
+	if apiKey == "" {
+		apiKey = r.URL.Query().Get("apikey")
+	}
+	if p.AnonKey != "" && apiKey != p.AnonKey {
+		return errors.New("missing or unknown apikey")
+	}
+
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if isServiceKey(apiKey) || isServiceKey(bearer) {
+		return errors.New("service role key")
+	}
+
+	return nil
+}
+
+// isServiceKey reports whether key is a Supabase secret key: either a new
+// style sb_secret_ key or a JWT whose role claim is service_role. The
+// signature isn't checked; Supabase does that.
+func isServiceKey(key string) bool {
+	if strings.HasPrefix(key, "sb_secret_") {
+		return true
+	}
+
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		Role string `json:"role"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return false
+	}
+	return claims.Role == "service_role"
+}
+
+// newSupabaseProxy returns a reverse proxy that forwards /supabase-proxy/*
+// to cfg.Target. Request and response bodies are streamed, and WebSocket
+// upgrades are passed through so Supabase Realtime works over ngrok.
+// cfg.Timeout limits how long Supabase has to start responding; upgraded
+// connections and streamed bodies are not cut off by it. Requests the
+// policy doesn't allow are refused and logged. CORS, including preflight
+// requests, is handled by the server-wide CORSPolicy in front of it.
+func newSupabaseProxy(cfg config.ProxyConfig) http.Handler {
+	policy := &ProxyPolicy{
+		Rules:        cfg.Allow,
+		AnonKey:      cfg.AnonKey,
+		MaxBodyBytes: cfg.MaxBodyBytes,
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: cfg.Timeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Transport:     transport,
+		FlushInterval: 100 * time.Millisecond,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// X-Forwarded-* and Forwarded headers from ngrok are already
+			// dropped by Rewrite; set our own for Supabase's logs.
+			pr.SetURL(cfg.Target)
+			pr.SetXForwarded()
+
+			// Avoid ngrok's interstitial warning page
+			pr.Out.Header.Del("Ngrok-Skip-Browser-Warning")
+			pr.Out.Header.Set("ngrok-skip-browser-warning", "true")
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			proxyRequests.WithLabelValues(proxyForwarded).Inc()
+
+			// Skip CORS headers from Supabase as we set our own
+			for _, header := range proxyCORSHeaders {
+				resp.Header.Del(header)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				logger(r.Context()).Warn("proxy denied", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr, "reason", "body too large", "limit", tooLarge.Limit)
+				proxyRequests.WithLabelValues(proxyTooLarge).Inc()
+				httpError(w, r, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			logger(r.Context()).Error("proxy failed", "method", r.Method, "path", r.URL.Path, "err", err)
+			proxyRequests.WithLabelValues(proxyUnavailable).Inc()
+			writeError(w, r, api.CodeUnavailable, "Supabase is unavailable", http.StatusBadGateway)
+		},
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := policy.Check(r); err != nil {
+			logger(r.Context()).Warn("proxy denied", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr, "reason", err)
+			proxyRequests.WithLabelValues(proxyDenied).Inc()
+			httpError(w, r, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if r.ContentLength > policy.MaxBodyBytes {
+			logger(r.Context()).Warn("proxy denied", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr, "reason", "body too large", "limit", policy.MaxBodyBytes)
+			proxyRequests.WithLabelValues(proxyTooLarge).Inc()
+			httpError(w, r, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, policy.MaxBodyBytes)
+
+		proxy.ServeHTTP(w, r)
+	})
+
+	return http.StripPrefix(proxyPrefix, handler)
+}
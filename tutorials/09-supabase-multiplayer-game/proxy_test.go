@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"crewmate-crisis/api"
+	"crewmate-crisis/config"
+)
+
+const testAnonKey = "anon-key"
+
+// newTestProxy starts a stand-in for Supabase and the proxy in front of
+// it, returning the proxy's URL
+func newTestProxy(t *testing.T, supabase http.Handler, configure func(*config.ProxyConfig)) string {
+	t.Helper()
+
+	upstream := httptest.NewServer(supabase)
+	t.Cleanup(upstream.Close)
+	target, _ := url.Parse(upstream.URL)
+
+	rules, err := config.ParseProxyRules(config.DefaultProxyAllow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.ProxyConfig{
+		Target:       target,
+		Timeout:      5 * time.Second,
+		Allow:        rules,
+		AnonKey:      testAnonKey,
+		MaxBodyBytes: 1 << 10,
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	proxy := httptest.NewServer(newSupabaseProxy(cfg))
+	t.Cleanup(proxy.Close)
+	return proxy.URL
+}
+
+func proxyRequest(t *testing.T, method, url string, body io.Reader) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("apikey", testAnonKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// errorCode reads the code from an api.ErrorResponse
+func errorCode(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	var body api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("error response isn't JSON: %v", err)
+	}
+	return body.Error.Code
+}
+
+func TestProxyForwardsRequests(t *testing.T) {
+	var got *http.Request
+	supabase := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Range", "0-0/1")
+		io.WriteString(w, `[{"id":1}]`)
+	})
+	proxyURL := newTestProxy(t, supabase, nil)
+
+	resp := proxyRequest(t, "GET", proxyURL+"/supabase-proxy/rest/v1/players?select=id", nil)
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || string(body) != `[{"id":1}]` {
+		t.Fatalf("response = %d %q, want 200 with Supabase's body", resp.StatusCode, body)
+	}
+	if got.URL.Path != "/rest/v1/players" || got.URL.RawQuery != "select=id" {
+		t.Errorf("Supabase got %s, want the path without the proxy prefix", got.URL)
+	}
+	if got.Header.Get("apikey") != testAnonKey {
+		t.Errorf("apikey = %q, want it forwarded", got.Header.Get("apikey"))
+	}
+	if got.Header.Get("X-Forwarded-Host") == "" {
+		t.Error("X-Forwarded-Host wasn't set")
+	}
+	if got.Header.Get("ngrok-skip-browser-warning") != "true" {
+		t.Error("ngrok-skip-browser-warning wasn't set")
+	}
+	if resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Supabase's CORS headers were passed through")
+	}
+	if resp.Header.Get("Content-Range") != "0-0/1" {
+		t.Error("Supabase's other headers were dropped")
+	}
+}
+
+func TestProxyWebSocketUpgrade(t *testing.T) {
+	// A stand-in Realtime server that switches protocols and echoes lines
+	supabase := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "expected an upgrade", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		line, _ := rw.ReadString('\n')
+		rw.WriteString("echo " + line)
+		rw.Flush()
+	})
+	proxyURL := newTestProxy(t, supabase, nil)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxyURL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "GET /supabase-proxy/realtime/v1/websocket?apikey="+testAnonKey+" HTTP/1.1\r\n"+
+		"Host: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+
+	io.WriteString(conn, "hello\n")
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "echo hello\n" {
+		t.Errorf("read %q, %v through the upgraded connection; want the echo", line, err)
+	}
+}
+
+func TestProxyTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+
+	supabase := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rest/v1/slow" {
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+
+		// Headers arrive in time; the body takes longer than the timeout
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(3 * timeout)
+		io.WriteString(w, "done")
+	})
+	proxyURL := newTestProxy(t, supabase, func(cfg *config.ProxyConfig) {
+		cfg.Timeout = timeout
+	})
+
+	resp := proxyRequest(t, "GET", proxyURL+"/supabase-proxy/rest/v1/slow", nil)
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("slow response: status = %d, want 502", resp.StatusCode)
+	}
+	if code := errorCode(t, resp); code != api.CodeUnavailable {
+		t.Errorf("slow response: code = %q, want %q", code, api.CodeUnavailable)
+	}
+
+	resp = proxyRequest(t, "GET", proxyURL+"/supabase-proxy/rest/v1/streamed", nil)
+	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "done" || err != nil {
+		t.Errorf("streamed response = %d %q, %v; want the whole body", resp.StatusCode, body, err)
+	}
+}
+
+func TestProxyBodyLimit(t *testing.T) {
+	const limit = 16
+
+	supabase := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	proxyURL := newTestProxy(t, supabase, func(cfg *config.ProxyConfig) {
+		rules, err := config.ParseProxyRules("PATCH /rest/v1/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Allow = rules
+		cfg.MaxBodyBytes = limit
+	})
+	target := proxyURL + "/supabase-proxy/rest/v1/room_players?id=eq.1"
+
+	resp := proxyRequest(t, "PATCH", target, strings.NewReader(strings.Repeat("x", limit)))
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("body at the limit: status = %d, want 204", resp.StatusCode)
+	}
+
+	resp = proxyRequest(t, "PATCH", target, strings.NewReader(strings.Repeat("x", limit+1)))
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("body over the limit: status = %d, want 413", resp.StatusCode)
+	}
+	if code := errorCode(t, resp); code != api.CodeTooLarge {
+		t.Errorf("body over the limit: code = %q, want %q", code, api.CodeTooLarge)
+	}
+
+	// Without a Content-Length the limit is applied while streaming
+	resp = proxyRequest(t, "PATCH", target, io.MultiReader(strings.NewReader(strings.Repeat("x", limit+1))))
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("streamed body over the limit: status = %d, want 413", resp.StatusCode)
+	}
+}
+
+func TestProxyTargetFallsBackToSupabaseURL(t *testing.T) {
+	reached := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer upstream.Close()
+
+	t.Setenv("SUPABASE_PROXY_TARGET", "")
+	t.Setenv("SUPABASE_URL", upstream.URL)
+	t.Setenv("SUPABASE_PROXY_ANON_KEY", testAnonKey)
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Proxy.Target.String() != upstream.URL {
+		t.Fatalf("proxy target = %s, want SUPABASE_URL %s", cfg.Proxy.Target, upstream.URL)
+	}
+
+	proxy := httptest.NewServer(newSupabaseProxy(cfg.Proxy))
+	defer proxy.Close()
+	resp := proxyRequest(t, "GET", proxy.URL+"/supabase-proxy/rest/v1/players", nil)
+	if resp.StatusCode != http.StatusOK || !reached {
+		t.Errorf("status = %d, reached SUPABASE_URL = %v", resp.StatusCode, reached)
+	}
+
+	t.Setenv("SUPABASE_PROXY_TARGET", "http://supabase.internal:54321")
+	cfg, err = config.Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Proxy.Target.String() != "http://supabase.internal:54321" {
+		t.Errorf("proxy target = %s, want SUPABASE_PROXY_TARGET to win", cfg.Proxy.Target)
+	}
+}
+
+// testJWT builds an unsigned JWT with the given claims
+func testJWT(claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + encode([]byte(claims)) + ".signature"
+}
+
+func TestProxyPolicyCheck(t *testing.T) {
+	rules, err := config.ParseProxyRules(config.DefaultProxyAllow + ", GET /auth/v1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &ProxyPolicy{Rules: rules, AnonKey: testAnonKey}
+	serviceJWT := testJWT(`{"role":"service_role"}`)
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		apiKey  string
+		auth    string
+		allowed bool
+	}{
+		{"table read", "GET", "/rest/v1/players?select=id", testAnonKey, "", true},
+		{"realtime key in query", "GET", "/realtime/v1/websocket?apikey=" + testAnonKey, "", "", true},
+		{"user JWT", "GET", "/rest/v1/players", testAnonKey, "Bearer " + testJWT(`{"role":"authenticated"}`), true},
+		{"prefix itself", "GET", "/rest/v1", testAnonKey, "", true},
+
+		{"dot dot", "GET", "/rest/v1/../../auth/v1/admin/users", testAnonKey, "", false},
+		{"dot", "GET", "/rest/v1/./players", testAnonKey, "", false},
+		{"double slash", "GET", "/rest/v1//players", testAnonKey, "", false},
+		{"trailing slash", "GET", "/rest/v1/players/", testAnonKey, "", false},
+		{"encoded slash", "GET", "/rest/v1%2F..%2Fpg", testAnonKey, "", false},
+		{"encoded dot dot", "GET", "/rest/v1/%2E%2E/pg", testAnonKey, "", false},
+
+		{"auth admin", "GET", "/auth/v1/admin/users", testAnonKey, "", false},
+		{"auth admin itself", "GET", "/auth/v1/admin", testAnonKey, "", false},
+		{"other auth route", "GET", "/auth/v1/user", testAnonKey, "", true},
+		{"pg meta", "GET", "/pg/tables", testAnonKey, "", false},
+		{"analytics", "GET", "/analytics/v1/logs", testAnonKey, "", false},
+		{"storage admin", "GET", "/storage/v1/admin/buckets", testAnonKey, "", false},
+
+		{"method not allowed", "POST", "/rest/v1/players", testAnonKey, "", false},
+		{"delete", "DELETE", "/rest/v1/players", testAnonKey, "", false},
+		{"task update", "PATCH", "/rest/v1/room_players?id=eq.1", testAnonKey, "", false},
+		{"path not allowed", "GET", "/storage/v1/object/avatars", testAnonKey, "", false},
+		{"prefix lookalike", "GET", "/rest/v10/players", testAnonKey, "", false},
+		{"root", "GET", "/", testAnonKey, "", false},
+
+		{"missing apikey", "GET", "/rest/v1/players", "", "", false},
+		{"wrong apikey", "GET", "/rest/v1/players", "other-key", "", false},
+		{"wrong apikey in query", "GET", "/realtime/v1/websocket?apikey=other-key", "", "", false},
+
+		{"service role bearer", "GET", "/rest/v1/players", testAnonKey, "Bearer " + serviceJWT, false},
+		{"secret key bearer", "GET", "/rest/v1/players", testAnonKey, "Bearer sb_secret_abc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.apiKey != "" {
+				req.Header.Set("apikey", tt.apiKey)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			err := policy.Check(req)
+			if tt.allowed && err != nil {
+				t.Errorf("Check(%s %s) = %v, want allowed", tt.method, tt.target, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("Check(%s %s) allowed it", tt.method, tt.target)
+			}
+		})
+	}
+}
+
+func TestProxyPolicyServiceKeyAsAPIKey(t *testing.T) {
+	// Without an anon key configured, any key but a service key passes
+	rules, err := config.ParseProxyRules(config.DefaultProxyAllow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &ProxyPolicy{Rules: rules}
+
+	for key, allowed := range map[string]bool{
+		"":                                 true,
+		"sb_publishable_abc":               true,
+		testJWT(`{"role":"anon"}`):         true,
+		"sb_secret_abc":                    false,
+		testJWT(`{"role":"service_role"}`): false,
+	} {
+		req := httptest.NewRequest("GET", "/rest/v1/players", nil)
+		req.Header.Set("apikey", key)
+		if err := policy.Check(req); (err == nil) != allowed {
+			t.Errorf("apikey %q: Check = %v, want allowed %v", key, err, allowed)
+		}
+	}
+}
+
+func TestIsServiceKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"sb_secret_abc123", true},
+		{testJWT(`{"role":"service_role","iss":"supabase"}`), true},
+		{"", false},
+		{"sb_publishable_abc123", false},
+		{testJWT(`{"role":"anon"}`), false},
+		{testJWT(`{"role":"authenticated","sub":"service_role"}`), false},
+		{testJWT(`{}`), false},
+		{testJWT(`not json`), false},
+		{"header.!!!.signature", false},
+		{"only.two", false},
+		{"a.b.c.d", false},
+	}
+	for _, tt := range tests {
+		if got := isServiceKey(tt.key); got != tt.want {
+			t.Errorf("isServiceKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
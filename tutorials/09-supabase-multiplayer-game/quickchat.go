@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"crewmate-crisis/api"
+)
+
+// Placeholders that quick-chat presets can contain
+const (
+	paramPlayer   = "player"
+	paramLocation = "location"
+)
+
+// quickChatPresets is the catalog offered to every room
+var quickChatPresets = []api.QuickChatPreset{
+	{ID: "saw_in", Text: "I saw {player} in {location}", Params: []string{paramPlayer, paramLocation}},
+	{ID: "was_with", Text: "I was with {player}", Params: []string{paramPlayer}},
+	{ID: "suspect", Text: "I think it's {player}", Params: []string{paramPlayer}},
+	{ID: "vouch", Text: "{player} is safe", Params: []string{paramPlayer}},
+	{ID: "doing_tasks", Text: "I was doing tasks in {location}", Params: []string{paramLocation}},
+	{ID: "where", Text: "Where was everyone?", Params: []string{}},
+	{ID: "not_me", Text: "It wasn't me!", Params: []string{}},
+	{ID: "agree", Text: "I agree", Params: []string{}},
+	{ID: "no_idea", Text: "I don't know yet", Params: []string{}},
+	{ID: "skip", Text: "Skip vote", Params: []string{}},
+}
+
+// quickChatLocations are the places on the ship (see the task list)
+var quickChatLocations = []string{
+	"Admin", "Cafeteria", "Electrical", "Engines", "Medbay", "Navigation", "Storage", "Weapons",
+}
+
+// findPreset looks up a preset by ID
+func findPreset(id string) (api.QuickChatPreset, bool) {
+	for _, preset := range quickChatPresets {
+		if preset.ID == id {
+			return preset, true
+		}
+	}
+	return api.QuickChatPreset{}, false
+}
+
+// renderPreset fills in a preset's placeholders. players maps player IDs
+// in the room to their usernames; the player param must be one of them.
+func renderPreset(preset api.QuickChatPreset, params map[string]string, players map[string]string) (string, error) {
+	// Replaced in one pass, so a username like "{location}" stays as it is
+	var replacements []string
+	for _, param := range preset.Params {
+		value := params[param]
+		switch param {
+		case paramPlayer:
+			username, ok := players[value]
+			if !ok {
+				return "", errors.New("Pick a player in this room")
+			}
+			value = username
+		case paramLocation:
+			valid := false
+			for _, location := range quickChatLocations {
+				if value == location {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return "", errors.New("Pick a location on the ship")
+			}
+		}
+		replacements = append(replacements, "{"+param+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(preset.Text), nil
+}
+
+// roomUsernames maps the IDs of players in a room to their usernames
+func (s *Server) roomUsernames(ctx context.Context, roomID string) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.id, p.username
+		FROM room_players rp
+		JOIN players p ON rp.player_id = p.id
+		WHERE rp.room_id = $1`,
+		roomID)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := make(map[string]string)
+	for rows.Next() {
+		var id, username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+		players[id] = username
+	}
+	return players, rows.Err()
+}
+
+func (s *Server) getQuickChat(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.QuickChatCatalog{
+		Presets:   quickChatPresets,
+		Locations: quickChatLocations,
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"crewmate-crisis/api"
+)
+
+func TestRenderPreset(t *testing.T) {
+	players := map[string]string{"p1": "ada", "p2": "<b>grace</b>", "p3": "{location}"}
+	preset := func(id string) api.QuickChatPreset {
+		t.Helper()
+		p, ok := findPreset(id)
+		if !ok {
+			t.Fatalf("no preset %s", id)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		preset  string
+		params  map[string]string
+		want    string
+		wantErr string
+	}{
+		{"no params", "agree", nil, "I agree", ""},
+		{"player", "suspect", map[string]string{"player": "p1"}, "I think it's ada", ""},
+		{"player and location", "saw_in", map[string]string{"player": "p1", "location": "Medbay"}, "I saw ada in Medbay", ""},
+		{"location", "doing_tasks", map[string]string{"location": "Electrical"}, "I was doing tasks in Electrical", ""},
+		{"extra params ignored", "where", map[string]string{"player": "p1"}, "Where was everyone?", ""},
+		// Usernames go in as they are; clients escape them when displaying
+		{"username kept as text", "vouch", map[string]string{"player": "p2"}, "<b>grace</b> is safe", ""},
+		{"placeholder in username", "saw_in", map[string]string{"player": "p3", "location": "Admin"}, "I saw {location} in Admin", ""},
+
+		{"player not in room", "suspect", map[string]string{"player": "p4"}, "", "Pick a player in this room"},
+		{"player missing", "was_with", nil, "", "Pick a player in this room"},
+		{"username instead of ID", "suspect", map[string]string{"player": "ada"}, "", "Pick a player in this room"},
+		{"unknown location", "doing_tasks", map[string]string{"location": "Bridge"}, "", "Pick a location on the ship"},
+		{"location case", "doing_tasks", map[string]string{"location": "medbay"}, "", "Pick a location on the ship"},
+		{"placeholder in location", "saw_in", map[string]string{"player": "p1", "location": "{player}"}, "", "Pick a location on the ship"},
+	}
+	for _, tt := range tests {
+		got, err := renderPreset(preset(tt.preset), tt.params, players)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: renderPreset = %q, %v; want error %q", tt.name, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: renderPreset = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestFindPreset(t *testing.T) {
+	if _, ok := findPreset("saw_in"); !ok {
+		t.Error("findPreset(saw_in) not found")
+	}
+	for _, id := range []string{"", "unknown", "SAW_IN"} {
+		if p, ok := findPreset(id); ok {
+			t.Errorf("findPreset(%q) = %+v, want not found", id, p)
+		}
+	}
+
+	// Every placeholder in a preset's text is one of its params
+	for _, p := range quickChatPresets {
+		params := map[string]string{paramPlayer: "p1", paramLocation: quickChatLocations[0]}
+		text, err := renderPreset(p, params, map[string]string{"p1": "ada"})
+		if err != nil || strings.ContainsAny(text, "{}") {
+			t.Errorf("preset %s renders as %q, %v", p.ID, text, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"crewmate-crisis/api"
+)
+
+// Points awarded at the end of each game
+const (
+	pointsCrewmateWin = 2
+	pointsImpostorWin = 3
+	pointsCorrectVote = 1
+	pointsPerTask     = 1
+)
+
+// awardPoints adds the results of a finished game to the room scoreboard
+func (s *Server) awardPoints(ctx context.Context, game *Game, winner string) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT rp.player_id,
+			(SELECT COUNT(*) FROM task_completions t
+			 WHERE t.game_id = $3 AND t.player_id = rp.player_id),
+			(SELECT COUNT(*) FROM votes v
+			 WHERE v.game_id = $3
+			   AND v.voter_id = rp.player_id
+			   AND v.suspect_id = $2)
+		FROM room_players rp
+		WHERE rp.room_id = $1`,
+		game.RoomID, game.ImpostorID, game.ID)
+
+	if err != nil {
+		return err
+	}
+
+	var results []api.PlayerScore
+	for rows.Next() {
+		var result api.PlayerScore
+		if err := rows.Scan(&result.PlayerID, &result.TasksCompleted, &result.CorrectVotes); err != nil {
+			rows.Close()
+			return err
+		}
+
+		if result.PlayerID == game.ImpostorID {
+			// The impostor only pretends to do tasks
+			result.TasksCompleted = 0
+			if winner == winnerImpostor {
+				result.ImpostorWins = 1
+				result.Points += pointsImpostorWin
+			}
+		} else if winner == winnerCrewmates {
+			result.CrewmateWins = 1
+			result.Points += pointsCrewmateWin
+		}
+
+		result.Points += result.CorrectVotes*pointsCorrectVote + result.TasksCompleted*pointsPerTask
+		results = append(results, result)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		_, err := s.db.ExecContext(ctx, `
+			INSERT INTO room_scores (room_id, player_id, points, games_played,
+				crewmate_wins, impostor_wins, correct_votes, tasks_completed)
+			VALUES ($1, $2, $3, 1, $4, $5, $6, $7)
+			ON CONFLICT (room_id, player_id) DO UPDATE SET
+				points = room_scores.points + EXCLUDED.points,
+				games_played = room_scores.games_played + 1,
+				crewmate_wins = room_scores.crewmate_wins + EXCLUDED.crewmate_wins,
+				impostor_wins = room_scores.impostor_wins + EXCLUDED.impostor_wins,
+				correct_votes = room_scores.correct_votes + EXCLUDED.correct_votes,
+				tasks_completed = room_scores.tasks_completed + EXCLUDED.tasks_completed`,
+			game.RoomID, result.PlayerID, result.Points,
+			result.CrewmateWins, result.ImpostorWins, result.CorrectVotes, result.TasksCompleted)
+
+		if err != nil {
+			return fmt.Errorf("failed to update score for player %s: %w", result.PlayerID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) getScoreboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	roomCode := vars["code"]
+
+	// Get room ID from room code
+	var roomID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id FROM game_rooms WHERE room_code = $1`,
+		roomCode).Scan(&roomID)
+
+	if err == sql.ErrNoRows {
+		apiError(w, r, api.CodeRoomNotFound, "Room not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		serverError(w, r, "Failed to get room", err)
+		return
+	}
+
+	var gamesPlayed int
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM games WHERE room_id = $1 AND ended_at IS NOT NULL`,
+		roomID).Scan(&gamesPlayed)
+
+	if err != nil {
+		serverError(w, r, "Failed to count games", err)
+		return
+	}
+
+	// Highest score first
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.id, p.username, p.avatar_color, rs.points, rs.games_played,
+			rs.crewmate_wins, rs.impostor_wins, rs.correct_votes, rs.tasks_completed
+		FROM room_scores rs
+		JOIN players p ON rs.player_id = p.id
+		WHERE rs.room_id = $1
+		ORDER BY rs.points DESC, p.username ASC`,
+		roomID)
+
+	if err != nil {
+		serverError(w, r, "Failed to get scoreboard", err)
+		return
+	}
+	defer rows.Close()
+
+	scores := make([]api.PlayerScore, 0)
+	for rows.Next() {
+		var score api.PlayerScore
+		err := rows.Scan(&score.PlayerID, &score.Username, &score.AvatarColor, &score.Points,
+			&score.GamesPlayed, &score.CrewmateWins, &score.ImpostorWins,
+			&score.CorrectVotes, &score.TasksCompleted)
+		if err != nil {
+			continue
+		}
+		scores = append(scores, score)
+	}
+
+	response := api.Scoreboard{
+		RoomCode:    roomCode,
+		GamesPlayed: gamesPlayed,
+		Scores:      scores,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
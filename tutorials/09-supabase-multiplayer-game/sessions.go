@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"crewmate-crisis/api"
+)
+
+// normalizeName makes roster checks ignore case and surrounding spaces
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// activeSession returns the ID of the class session currently running,
+// or an empty string when rooms are open to any name.
+func (s *Server) activeSession(ctx context.Context) (string, error) {
+	var sessionID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id FROM class_sessions
+		WHERE ended_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1`).Scan(&sessionID)
+
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return sessionID, err
+}
+
+// onRoster reports whether a display name is on a session's roster
+func (s *Server) onRoster(ctx context.Context, sessionID, username string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM session_roster
+			WHERE session_id = $1 AND normalized_name = $2
+		)`,
+		sessionID, normalizeName(username)).Scan(&exists)
+
+	return exists, err
+}
+
+// nameTaken reports whether a display name is already used in a room
+func (s *Server) nameTaken(ctx context.Context, roomID, username string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM room_players rp
+			JOIN players p ON rp.player_id = p.id
+			WHERE rp.room_id = $1 AND LOWER(TRIM(p.username)) = $2
+		)`,
+		roomID, normalizeName(username)).Scan(&exists)
+
+	return exists, err
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req api.CreateSessionRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	// Drop blank and duplicate names
+	seen := make(map[string]bool)
+	roster := make([]string, 0, len(req.Roster))
+	for _, name := range req.Roster {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[normalizeName(name)] {
+			continue
+		}
+		seen[normalizeName(name)] = true
+		roster = append(roster, name)
+	}
+
+	if len(roster) == 0 {
+		httpError(w, r, "Roster must contain at least one name", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		serverError(w, r, "Failed to create session", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Only one class runs at a time; starting a new one ends the previous
+	_, err = tx.ExecContext(ctx, `
+		UPDATE class_sessions SET ended_at = NOW() WHERE ended_at IS NULL`)
+
+	if err != nil {
+		serverError(w, r, "Failed to end previous session", err)
+		return
+	}
+
+	session := api.ClassSession{Name: req.Name, Roster: roster}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO class_sessions (name)
+		VALUES ($1)
+		RETURNING id, created_at`,
+		req.Name).Scan(&session.ID, &session.CreatedAt)
+
+	if err != nil {
+		serverError(w, r, "Failed to create session", err)
+		return
+	}
+
+	for _, name := range roster {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO session_roster (session_id, display_name, normalized_name)
+			VALUES ($1, $2, $3)`,
+			session.ID, name, normalizeName(name))
+
+		if err != nil {
+			serverError(w, r, "Failed to save roster", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		serverError(w, r, "Failed to create session", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+func (s *Server) endSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+	if !isUUID(sessionID) {
+		httpError(w, r, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE class_sessions SET ended_at = NOW()
+		WHERE id = $1 AND ended_at IS NULL`,
+		sessionID)
+
+	if err != nil {
+		serverError(w, r, "Failed to end session", err)
+		return
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		httpError(w, r, "Active session not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.SessionEnded{
+		Status:    "session_ended",
+		SessionID: sessionID,
+	})
+}
+
+func (s *Server) getSessionReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+	if !isUUID(sessionID) {
+		httpError(w, r, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	var session api.ClassSession
+	var endedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, name, created_at, ended_at FROM class_sessions WHERE id = $1`,
+		sessionID).Scan(&session.ID, &session.Name, &session.CreatedAt, &endedAt)
+
+	if err == sql.ErrNoRows {
+		httpError(w, r, "Session not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		serverError(w, r, "Failed to get session", err)
+		return
+	}
+
+	if endedAt.Valid {
+		session.EndedAt = &endedAt.Time
+	}
+
+	// Roster
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT display_name FROM session_roster
+		WHERE session_id = $1
+		ORDER BY display_name`,
+		sessionID)
+
+	if err != nil {
+		serverError(w, r, "Failed to get roster", err)
+		return
+	}
+
+	session.Roster = make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			continue
+		}
+		session.Roster = append(session.Roster, name)
+	}
+	rows.Close()
+
+	// Rooms created during the session
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT gr.id, gr.room_code, gr.status, gr.created_at,
+			(SELECT COUNT(*) FROM games g WHERE g.room_id = gr.id AND g.ended_at IS NOT NULL)
+		FROM game_rooms gr
+		WHERE gr.session_id = $1
+		ORDER BY gr.created_at ASC`,
+		sessionID)
+
+	if err != nil {
+		serverError(w, r, "Failed to get rooms", err)
+		return
+	}
+
+	var roomIDs []string
+	rooms := make([]api.SessionRoomReport, 0)
+	for rows.Next() {
+		var roomID string
+		room := api.SessionRoomReport{Players: []string{}, Scores: []api.PlayerScore{}}
+		if err := rows.Scan(&roomID, &room.RoomCode, &room.Status, &room.CreatedAt, &room.GamesPlayed); err != nil {
+			continue
+		}
+		roomIDs = append(roomIDs, roomID)
+		rooms = append(rooms, room)
+	}
+	rows.Close()
+
+	// Players and scores in each room
+	for i, roomID := range roomIDs {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT p.id, p.username, p.avatar_color,
+				COALESCE(rs.points, 0), COALESCE(rs.games_played, 0),
+				COALESCE(rs.crewmate_wins, 0), COALESCE(rs.impostor_wins, 0),
+				COALESCE(rs.correct_votes, 0), COALESCE(rs.tasks_completed, 0)
+			FROM room_players rp
+			JOIN players p ON rp.player_id = p.id
+			LEFT JOIN room_scores rs ON rs.room_id = rp.room_id AND rs.player_id = rp.player_id
+			WHERE rp.room_id = $1
+			ORDER BY COALESCE(rs.points, 0) DESC, p.username ASC`,
+			roomID)
+
+		if err != nil {
+			serverError(w, r, "Failed to get room players", err)
+			return
+		}
+
+		for rows.Next() {
+			var score api.PlayerScore
+			err := rows.Scan(&score.PlayerID, &score.Username, &score.AvatarColor, &score.Points,
+				&score.GamesPlayed, &score.CrewmateWins, &score.ImpostorWins,
+				&score.CorrectVotes, &score.TasksCompleted)
+			if err != nil {
+				continue
+			}
+			rooms[i].Players = append(rooms[i].Players, score.Username)
+			rooms[i].Scores = append(rooms[i].Scores, score)
+		}
+		rows.Close()
+	}
+
+	response := api.SessionReport{
+		Session: session,
+		Rooms:   rooms,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT cs.id, cs.name, cs.created_at, cs.ended_at,
+			(SELECT COUNT(*) FROM game_rooms gr WHERE gr.session_id = cs.id),
+			(SELECT COUNT(*) FROM session_roster sr WHERE sr.session_id = cs.id)
+		FROM class_sessions cs
+		ORDER BY cs.created_at DESC`)
+
+	if err != nil {
+		serverError(w, r, "Failed to list sessions", err)
+		return
+	}
+	defer rows.Close()
+
+	sessions := make([]api.SessionSummary, 0)
+	for rows.Next() {
+		var session api.SessionSummary
+		var endedAt sql.NullTime
+		err := rows.Scan(&session.ID, &session.Name, &session.CreatedAt, &endedAt, &session.RoomCount, &session.RosterSize)
+		if err != nil {
+			continue
+		}
+
+		session.Active = !endedAt.Valid
+		if endedAt.Valid {
+			session.EndedAt = &endedAt.Time
+		}
+		sessions = append(sessions, session)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
@@ -130,7 +130,7 @@ echo "Next steps:"
 echo "1. Create a .envrc file with your Supabase keys (see above)"
 echo "2. Run: direnv allow"
 echo "3. Update static/app.js with your SUPABASE_ANON_KEY"
-echo "4. Run: go run main.go"
+echo "4. Run: go run ."
 echo "5. Open: http://localhost:8080"
 echo ""
 echo "Services running:"
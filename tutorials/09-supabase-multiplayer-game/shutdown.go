@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// gameTimers runs delayed game changes, such as closing a meeting when
+// its time runs out. On shutdown, Drain runs the ones still waiting
+// instead of dropping them, so no room is left stuck in a meeting.
+type gameTimers struct {
+	timeout time.Duration // for each callback's context
+
+	mu       sync.Mutex
+	pending  map[*time.Timer]func(context.Context)
+	draining bool
+	running  sync.WaitGroup
+}
+
+func newGameTimers(timeout time.Duration) *gameTimers {
+	return &gameTimers{
+		timeout: timeout,
+		pending: make(map[*time.Timer]func(context.Context)),
+	}
+}
+
+// AfterFunc calls f after d, or right away once draining has started
+func (t *gameTimers) AfterFunc(d time.Duration, f func(ctx context.Context)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.running.Add(1)
+	if t.draining {
+		go t.run(f)
+		return
+	}
+
+	// The callback can't remove itself before it's added: it needs t.mu
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		t.mu.Lock()
+		delete(t.pending, timer)
+		t.mu.Unlock()
+		t.run(f)
+	})
+	t.pending[timer] = f
+}
+
+func (t *gameTimers) run(f func(ctx context.Context)) {
+	defer t.running.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	f(ctx)
+}
+
+// Drain runs every waiting timer now and waits for them to finish, or
+// for ctx to be done
+func (t *gameTimers) Drain(ctx context.Context) error {
+	t.mu.Lock()
+	t.draining = true
+	for timer, f := range t.pending {
+		// A timer that already fired removes itself and runs on its own
+		if timer.Stop() {
+			delete(t.pending, timer)
+			go t.run(f)
+		}
+	}
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withTimeout cancels a request's context, and with it any database
+// queries the request is running, after d
+func withTimeout(d time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// listener is an http.Server and how to start it
+type listener struct {
+	name   string
+	server *http.Server
+	serve  func() error
+}
+
+// serveUntil runs the listeners until ctx is done (usually on SIGINT or
+// SIGTERM) or the first listener fails, then shuts them all down: new
+// connections are refused and requests in progress get until timeout
+// to finish. Failures of optional listeners (HTTPS) are only logged.
+func serveUntil(ctx context.Context, timeout time.Duration, required *listener, optional ...*listener) error {
+	failed := make(chan error, 1)
+	for _, l := range append([]*listener{required}, optional...) {
+		go func(l *listener) {
+			err := l.serve()
+			if errors.Is(err, http.ErrServerClosed) {
+				return
+			}
+			if l == required {
+				failed <- fmt.Errorf("%s server: %w", l.name, err)
+				return
+			}
+			slog.Error("server error", "server", l.name, "err", err)
+		}(l)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down, finishing requests in progress")
+	case err = <-failed:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, l := range append([]*listener{required}, optional...) {
+		wg.Add(1)
+		go func(l *listener) {
+			defer wg.Done()
+			if err := l.server.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("server didn't shut down cleanly", "server", l.name, "err", err)
+			}
+		}(l)
+	}
+	wg.Wait()
+	return err
+}
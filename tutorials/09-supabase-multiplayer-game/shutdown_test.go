@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGameTimersDrain(t *testing.T) {
+	timers := newGameTimers(time.Minute)
+
+	var waiting, fired, deadlines atomic.Int32
+	count := func(n *atomic.Int32) func(context.Context) {
+		return func(ctx context.Context) {
+			if _, ok := ctx.Deadline(); ok {
+				deadlines.Add(1)
+			}
+			n.Add(1)
+		}
+	}
+
+	// One timer still waiting, one that has already fired
+	timers.AfterFunc(time.Hour, count(&waiting))
+	timers.AfterFunc(0, count(&fired))
+	deadline := time.Now().Add(time.Second)
+	for fired.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := timers.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waiting.Load() != 1 || fired.Load() != 1 {
+		t.Errorf("after Drain: waiting timer ran %d times, fired timer %d; want once each", waiting.Load(), fired.Load())
+	}
+	if deadlines.Load() != 2 {
+		t.Errorf("%d of 2 callbacks had a deadline", deadlines.Load())
+	}
+
+	// Once draining, new timers run right away
+	var late atomic.Int32
+	timers.AfterFunc(time.Hour, count(&late))
+	if err := timers.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if late.Load() != 1 {
+		t.Errorf("timer added while draining ran %d times, want 1", late.Load())
+	}
+}
+
+func TestGameTimersDrainTimeout(t *testing.T) {
+	timers := newGameTimers(time.Minute)
+
+	release := make(chan struct{})
+	defer close(release)
+	timers.AfterFunc(time.Hour, func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := timers.Drain(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Drain with a stuck timer = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -41,6 +41,7 @@ const supabase = window.supabase.createClient(SUPABASE_URL, SUPABASE_ANON_KEY, s
 // Game state
 let gameState = {
     playerId: null,
+    playerToken: null,
     playerName: '',
     playerColor: 'blue',
     roomId: null,
@@ -48,12 +49,13 @@ let gameState = {
     isHost: false,
     role: null,
     isAlive: true,
-    currentRound: 1,
     taskProgress: 0,
     totalTasks: 5,
     players: [],
     subscriptions: null,  // Track active subscriptions
-    pollingInterval: null // Track polling interval
+    pollingInterval: null, // Track polling interval
+    lastMessageId: null,  // Chat cursor for polling
+    seenMessageIds: new Set()
 };
 
 // Screens
@@ -64,11 +66,74 @@ const screens = {
     gameOver: document.getElementById('gameOverScreen')
 };
 
+// Wording for the error codes the game server returns. Codes not listed
+// here use the server's message.
+const errorMessages = {
+    room_not_found: 'No room with that code. Check the code and try again.',
+    room_full: 'That room is full. Ask the host to start a new room.',
+    name_taken: 'Someone in that room already has that name. Try another one.',
+    not_on_roster: "That name isn't on the class list. Use the name your teacher gave you.",
+    game_in_progress: 'That game has already started.',
+    not_enough_players: 'You need at least 3 players to start.',
+    already_voted: 'You already voted this round.',
+    no_meeting: 'Voting has closed for this meeting.',
+    no_game: "There's no game running in this room.",
+    unauthorized: 'The server no longer recognises you. Rejoin the room.',
+    internal_error: 'Something went wrong on the game server. Please try again.'
+};
+
+// Escape text from players (names, chat) before it goes into HTML
+function escapeHtml(text) {
+    const div = document.createElement('div');
+    div.textContent = text == null ? '' : String(text);
+    return div.innerHTML.replace(/"/g, '&quot;').replace(/'/g, '&#39;');
+}
+
+// Replace a select's options; labels are set as text, never parsed as HTML
+function setOptions(select, items, valueOf, labelOf) {
+    select.replaceChildren(...items.map(item => {
+        const option = document.createElement('option');
+        option.value = valueOf(item);
+        option.textContent = labelOf(item);
+        return option;
+    }));
+}
+
+// Headers for requests made as this player. The server checks the token
+// it gave us when we joined, so nobody else can vote or chat as us.
+function playerHeaders() {
+    return {
+        'Content-Type': 'application/json',
+        'X-Player-Token': gameState.playerToken || ''
+    };
+}
+
+// Turn an error response into a message for the player. Server errors
+// include the request ID so the teacher can find them in the logs.
+async function errorMessage(response) {
+    let error = null;
+    try {
+        error = (await response.json()).error;
+    } catch (e) {
+        // Not from the game server (e.g. an ngrok error page)
+    }
+    if (!error) {
+        return `Something went wrong (HTTP ${response.status}). Please try again.`;
+    }
+
+    const message = errorMessages[error.code] || error.message;
+    if (response.status >= 500 && error.request_id) {
+        return `${message} (request ${error.request_id})`;
+    }
+    return message;
+}
+
 // Initialize
 document.addEventListener('DOMContentLoaded', () => {
     setupEventListeners();
     setupColorSelector();
     loadConnectionInfo();
+    prefillRoomCode();
 });
 
 function setupEventListeners() {
@@ -95,10 +160,12 @@ function setupEventListeners() {
         if (e.key === 'Enter') sendChat();
     });
     document.getElementById('skipVoteBtn').addEventListener('click', () => submitVote(null));
+    document.getElementById('quickChatPreset').addEventListener('change', updateQuickChatParams);
+    document.getElementById('sendQuickChatBtn').addEventListener('click', sendQuickChat);
 
     // Task checkboxes
     document.querySelectorAll('.task-checkbox').forEach(checkbox => {
-        checkbox.addEventListener('change', updateTaskProgress);
+        checkbox.addEventListener('change', () => completeTask(checkbox));
     });
 
     // Game over screen
@@ -118,6 +185,21 @@ function setupColorSelector() {
     document.querySelector('[data-color="blue"]').classList.add('ring-4', 'ring-white');
 }
 
+// Join links and QR codes open the game with ?room=CODE
+function prefillRoomCode() {
+    const roomCode = new URLSearchParams(window.location.search).get('room');
+    if (roomCode) {
+        document.getElementById('roomCodeInput').value = roomCode.toUpperCase();
+        document.getElementById('username').focus();
+    }
+}
+
+function showRoomQr(roomCode) {
+    const qr = document.getElementById('roomQr');
+    qr.src = `/api/rooms/${roomCode}/qr`;
+    qr.classList.remove('hidden');
+}
+
 async function loadConnectionInfo() {
     try {
         const response = await fetch('/api/health');
@@ -170,52 +252,31 @@ async function createRoom() {
     gameState.playerName = username;
 
     try {
-        // Use Supabase's auto-generated API to create player
-        const { data: player, error: playerError } = await supabase
-            .from('players')
-            .insert({
+        // Create the room through the game server so class rosters are enforced
+        const response = await fetch('/api/rooms', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
                 username: username,
                 avatar_color: gameState.playerColor
             })
-            .select()
-            .single();
-
-        if (playerError) throw playerError;
-
-        gameState.playerId = player.id;
-
-        // Generate room code
-        const roomCode = generateRoomCode();
+        });
 
-        // Create game room using Supabase API
-        const { data: room, error: roomError } = await supabase
-            .from('game_rooms')
-            .insert({
-                room_code: roomCode,
-                host_id: player.id,
-                status: 'waiting'
-            })
-            .select()
-            .single();
+        if (!response.ok) {
+            throw new Error(await errorMessage(response));
+        }
 
-        if (roomError) throw roomError;
+        const room = await response.json();
+        const roomCode = room.room_code;
 
-        gameState.roomId = room.id;
+        gameState.playerId = room.player_id;
+        gameState.playerToken = room.player_token;
+        gameState.roomId = room.room_id;
         gameState.roomCode = roomCode;
         gameState.isHost = true;
 
-        // Add player to room
-        const { error: joinError } = await supabase
-            .from('room_players')
-            .insert({
-                room_id: room.id,
-                player_id: player.id,
-                is_alive: true
-            });
-
-        if (joinError) throw joinError;
-
         document.getElementById('roomCodeDisplay').textContent = roomCode;
+        showRoomQr(roomCode);
         showScreen('lobby');
 
         // Subscribe to room updates using Supabase Realtime
@@ -230,15 +291,6 @@ async function createRoom() {
     }
 }
 
-function generateRoomCode() {
-    const letters = 'ABCDEFGHIJKLMNOPQRSTUVWXYZ';
-    let code = '';
-    for (let i = 0; i < 6; i++) {
-        code += letters[Math.floor(Math.random() * letters.length)];
-    }
-    return code;
-}
-
 async function joinRoom() {
     const username = document.getElementById('username').value.trim();
     const roomCode = document.getElementById('roomCodeInput').value.trim().toUpperCase();
@@ -252,56 +304,31 @@ async function joinRoom() {
     gameState.roomCode = roomCode;
 
     try {
-        // Find room by code using Supabase API
-        const { data: rooms, error: roomError } = await supabase
-            .from('game_rooms')
-            .select('*')
-            .eq('room_code', roomCode)
-            .single();
-
-        if (roomError || !rooms) {
-            throw new Error('Room not found');
-        }
-
-        // Check if room is full
-        const { count, error: countError } = await supabase
-            .from('room_players')
-            .select('*', { count: 'exact', head: true })
-            .eq('room_id', rooms.id);
-
-        if (count >= 10) {
-            throw new Error('Room is full');
-        }
-
-        // Create player using Supabase API
-        const { data: player, error: playerError } = await supabase
-            .from('players')
-            .insert({
+        // Join through the game server, which checks capacity and the class roster
+        const response = await fetch('/api/rooms/join', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                room_code: roomCode,
                 username: username,
                 avatar_color: gameState.playerColor
             })
-            .select()
-            .single();
-
-        if (playerError) throw playerError;
+        });
 
-        // Add player to room
-        const { error: joinError } = await supabase
-            .from('room_players')
-            .insert({
-                room_id: rooms.id,
-                player_id: player.id,
-                is_alive: true
-            });
+        if (!response.ok) {
+            throw new Error(await errorMessage(response));
+        }
 
-        if (joinError) throw joinError;
+        const room = await response.json();
 
-        gameState.playerId = player.id;
-        gameState.roomId = rooms.id;
+        gameState.playerId = room.player_id;
+        gameState.playerToken = room.player_token;
+        gameState.roomId = room.room_id;
         gameState.roomCode = roomCode;
         gameState.isHost = false;
 
         document.getElementById('roomCodeDisplay').textContent = roomCode;
+        showRoomQr(roomCode);
         showScreen('lobby');
 
         // Subscribe to room updates using Supabase Realtime
@@ -356,15 +383,15 @@ function startPollingUpdates() {
             // Use Supabase API to check game status
             const { data: room, error } = await supabase
                 .from('game_rooms')
-                .select('status, impostor_id')
+                .select('status')
                 .eq('id', gameState.roomId)
                 .single();
 
             if (!error && room) {
-                // Check if game started
+                // Check if game started. Only the server knows who the
+                // impostor is, and it only tells the impostor.
                 if (room.status === 'playing' && !gameState.role) {
-                    gameState.impostorId = room.impostor_id; // Store impostor ID
-                    gameState.role = room.impostor_id === gameState.playerId ? 'Impostor' : 'Crewmate';
+                    gameState.role = await fetchRole();
                     console.log('🎮 Game started! Role:', gameState.role);
                     showScreen('game');
                     initializeGame();
@@ -434,7 +461,7 @@ function updatePlayersFromAPI(players, hostId) {
             const colorClass = `bg-${player.avatar_color}-500`;
             playerDiv.innerHTML = `
                 <div class="w-16 h-16 ${colorClass} rounded-full mx-auto mb-2 player-avatar"></div>
-                <p class="font-semibold">${player.username}</p>
+                <p class="font-semibold">${escapeHtml(player.username)}</p>
                 ${player.id === hostId ? '<span class="text-xs text-yellow-400">Host</span>' : ''}
             `;
 
@@ -448,7 +475,7 @@ async function updatePlayersList() {
         // Use Supabase API to get room and players
         const { data: room, error: roomError } = await supabase
             .from('game_rooms')
-            .select('*, room_players(*, players(*))')
+            .select('id, host_id, status, room_players(player_id, is_alive, tasks_completed, players(id, username, avatar_color))')
             .eq('id', gameState.roomId)
             .single();
 
@@ -475,7 +502,7 @@ async function updatePlayersList() {
                 const colorClass = `bg-${player.avatar_color}-500`;
                 playerDiv.innerHTML = `
                     <div class="w-16 h-16 ${colorClass} rounded-full mx-auto mb-2 player-avatar"></div>
-                    <p class="font-semibold">${player.username}</p>
+                    <p class="font-semibold">${escapeHtml(player.username)}</p>
                     ${player.id === room.host_id ? '<span class="text-xs text-yellow-400">Host</span>' : ''}
                 `;
 
@@ -494,8 +521,8 @@ async function updatePlayersList() {
         try {
             const response = await fetch(`/api/rooms/${gameState.roomCode}`);
             const room = await response.json();
-            if (room.room_players) {
-                updatePlayersFromAPI(room.room_players, room.host_id);
+            if (room.players) {
+                updatePlayersFromAPI(room.players, room.host_id);
             }
         } catch (fallbackError) {
             console.error('Fallback also failed:', fallbackError);
@@ -503,14 +530,27 @@ async function updatePlayersList() {
     }
 }
 
+// Ask the server whether we're the impostor
+async function fetchRole() {
+    const response = await fetch(`/api/rooms/${gameState.roomCode}?player_id=${gameState.playerId}`, {
+        headers: playerHeaders()
+    });
+    if (!response.ok) {
+        throw new Error(await errorMessage(response));
+    }
+    const room = await response.json();
+    return room.impostor_id === gameState.playerId ? 'Impostor' : 'Crewmate';
+}
+
 async function startGame() {
     try {
-        const response = await fetch(`/api/rooms/${gameState.roomCode}/start`, {
-            method: 'POST'
+        const response = await fetch(`/api/rooms/${gameState.roomCode}/start?player_id=${gameState.playerId}`, {
+            method: 'POST',
+            headers: playerHeaders()
         });
 
         if (!response.ok) {
-            throw new Error('Failed to start game');
+            throw new Error(await errorMessage(response));
         }
 
         const data = await response.json();
@@ -518,7 +558,7 @@ async function startGame() {
 
     } catch (error) {
         console.error('Error starting game:', error);
-        alert('Failed to start game. Make sure you have at least 3 players.');
+        alert(error.message);
     }
 }
 
@@ -537,24 +577,52 @@ function initializeGame() {
     // Load game players
     updateGamePlayers();
 
+    // Start each game with a fresh task list
+    document.querySelectorAll('.task-checkbox').forEach(checkbox => {
+        checkbox.checked = false;
+        checkbox.disabled = false;
+    });
+
     // Initialize task progress
     updateTaskProgress();
 
+    // Load quick-chat phrases and the room's chat settings
+    setupQuickChat();
+
     // Start polling for messages (since WebSockets aren't working)
     setInterval(async () => {
         try {
-            const response = await fetch(`/api/rooms/${gameState.roomCode}/messages`);
+            // Pass our player ID and token so the server includes ghost/impostor chat we
+            // may read, and the last message we have so only new messages come back
+            const params = new URLSearchParams({ player_id: gameState.playerId });
+            if (gameState.lastMessageId) {
+                params.set('after', gameState.lastMessageId);
+            }
+
+            const response = await fetch(`/api/rooms/${gameState.roomCode}/messages?${params}`, {
+                headers: { 'X-Player-Token': gameState.playerToken || '' }
+            });
+            const chatMessages = document.getElementById('chatMessages');
+
+            if (response.status === 400 && gameState.lastMessageId) {
+                // Our cursor is gone (the chat was wiped) - start over
+                gameState.lastMessageId = null;
+                gameState.seenMessageIds.clear();
+                if (chatMessages) chatMessages.innerHTML = '';
+                return;
+            }
+
             if (response.ok) {
                 const messages = await response.json();
-                // Display new messages
-                const chatMessages = document.getElementById('chatMessages');
-                if (chatMessages && messages && messages.length > 0) {
-                    // Only update if we have more messages than currently displayed
-                    if (messages.length > chatMessages.children.length) {
-                        // Clear and redisplay all messages
-                        chatMessages.innerHTML = '';
-                        messages.forEach(msg => displayMessage(msg));
+                // Display new messages (our own are already shown when sent)
+                messages.forEach(msg => {
+                    if (!gameState.seenMessageIds.has(msg.id)) {
+                        gameState.seenMessageIds.add(msg.id);
+                        displayMessage(msg);
                     }
+                });
+                if (messages.length > 0) {
+                    gameState.lastMessageId = messages[messages.length - 1].id;
                 }
             }
         } catch (error) {
@@ -577,46 +645,67 @@ function updateGamePlayers() {
 
         playerDiv.innerHTML = `
             <div class="w-12 h-12 ${colorClass} rounded-full mx-auto mb-1 player-avatar ${opacity}"></div>
-            <p class="text-xs ${!isAlive ? 'line-through' : ''}">${player.username}</p>
+            <p class="text-xs ${!isAlive ? 'line-through' : ''}">${escapeHtml(player.username)}</p>
         `;
 
         gamePlayers.appendChild(playerDiv);
     });
 }
 
-async function updateTaskProgress() {
+function updateTaskProgress() {
     const completed = document.querySelectorAll('.task-checkbox:checked').length;
     const total = document.querySelectorAll('.task-checkbox').length;
 
     gameState.taskProgress = completed;
     gameState.totalTasks = total;
 
-    // Update task progress in database via Supabase
-    if (gameState.role === 'Crewmate') {
-        try {
-            const { error } = await supabase
-                .from('room_players')
-                .update({ tasks_completed: completed })
-                .eq('room_id', gameState.roomId)
-                .eq('player_id', gameState.playerId);
-
-            if (error) {
-                console.error('Error updating task progress:', error);
-            } else {
-                console.log(`📋 Task progress updated: ${completed}/${total}`);
-            }
-        } catch (error) {
-            console.error('Error updating task progress:', error);
-        }
-    }
-
     // Update local UI
     updateTaskProgressBar(completed, total);
+}
+
+// Report a finished task to the game server, which counts each task once
+// and decides when the crewmates have won
+async function completeTask(checkbox) {
+    if (!checkbox.checked || gameState.role !== 'Crewmate') {
+        updateTaskProgress();
+        return;
+    }
+
+    checkbox.disabled = true;
+    updateTaskProgress();
+
+    const undo = () => {
+        checkbox.checked = false;
+        checkbox.disabled = false;
+        updateTaskProgress();
+    };
+
+    try {
+        const response = await fetch(`/api/rooms/${gameState.roomCode}/task`, {
+            method: 'POST',
+            headers: playerHeaders(),
+            body: JSON.stringify({
+                player_id: gameState.playerId,
+                task_id: checkbox.id
+            })
+        });
+
+        if (!response.ok) {
+            const message = await errorMessage(response);
+            console.error('Error completing task:', message);
+            document.getElementById('statusMessage').textContent = message;
+            undo();
+            return;
+        }
 
-    // Check for crewmate win condition
-    if (gameState.role === 'Crewmate' && completed === total) {
-        // Send task completion to server
-        completeAllTasks();
+        const result = await response.json();
+        console.log(`📋 Task ${checkbox.id}: ${result.status}`);
+        if (result.winner) {
+            showGameOver(result.winner, 'All tasks completed! Crewmates Win!');
+        }
+    } catch (error) {
+        console.error('Error completing task:', error);
+        undo();
     }
 }
 
@@ -641,21 +730,16 @@ async function updateOverallTaskProgress() {
         }
 
         if (roomPlayers && roomPlayers.length > 0) {
-            // Calculate total tasks completed by all crewmates
+            // Calculate total tasks completed by all crewmates. The
+            // impostor can't complete tasks, so counting everyone's is
+            // the same as counting the crewmates'.
             let totalCompleted = 0;
-            let totalTasks = 0;
-            let crewmateCount = 0;
-
             roomPlayers.forEach(rp => {
-                // Skip the impostor (they don't have real tasks)
-                if (rp.player_id !== gameState.impostorId) {
-                    totalCompleted += rp.tasks_completed || 0;
-                    crewmateCount++;
-                }
+                totalCompleted += rp.tasks_completed || 0;
             });
 
-            // Each crewmate has 5 tasks
-            totalTasks = crewmateCount * 5;
+            // Everyone but the impostor has 5 tasks
+            const totalTasks = (roomPlayers.length - 1) * 5;
 
             // Update the overall progress bar (only if we're not updating our own tasks)
             if (!document.activeElement.classList.contains('task-checkbox')) {
@@ -673,25 +757,12 @@ async function updateOverallTaskProgress() {
     }
 }
 
-async function completeAllTasks() {
-    try {
-        await fetch(`/api/rooms/${gameState.roomCode}/task`, {
-            method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({
-                player_id: gameState.playerId,
-                task_id: 'all'
-            })
-        });
-    } catch (error) {
-        console.error('Error completing tasks:', error);
-    }
-}
-
 async function callEmergency() {
     try {
         await fetch(`/api/rooms/${gameState.roomCode}/emergency`, {
-            method: 'POST'
+            method: 'POST',
+            headers: playerHeaders(),
+            body: JSON.stringify({ player_id: gameState.playerId })
         });
 
         startVoting();
@@ -717,7 +788,7 @@ function startVoting() {
         voteBtn.className = 'bg-gray-600 hover:bg-gray-500 px-3 py-2 rounded-lg text-sm';
         voteBtn.innerHTML = `
             <div class="w-8 h-8 bg-${player.avatar_color}-500 rounded-full mx-auto mb-1"></div>
-            <p>${player.username}</p>
+            <p>${escapeHtml(player.username)}</p>
         `;
         voteBtn.onclick = () => submitVote(player.id);
 
@@ -727,18 +798,21 @@ function startVoting() {
 
 async function submitVote(suspectId) {
     try {
-        // Submit vote to Supabase
-        const { error } = await supabase
-            .from('votes')
-            .insert({
+        // Submit vote to the game server so it can count votes and score the game
+        const response = await fetch(`/api/rooms/${gameState.roomCode}/vote`, {
+            method: 'POST',
+            headers: playerHeaders(),
+            body: JSON.stringify({
                 room_id: gameState.roomId,
                 voter_id: gameState.playerId,
-                suspect_id: suspectId,
-                round: gameState.currentRound
-            });
+                suspect_id: suspectId || ''
+            })
+        });
 
-        if (error) {
-            console.error('Error submitting vote:', error);
+        if (!response.ok) {
+            const message = await errorMessage(response);
+            console.error('Error submitting vote:', message);
+            document.getElementById('statusMessage').textContent = message;
             return;
         }
 
@@ -748,181 +822,156 @@ async function submitVote(suspectId) {
         document.getElementById('votingArea').classList.add('hidden');
         document.getElementById('statusMessage').textContent = 'Vote submitted. Waiting for others...';
 
-        // Check if all alive players have voted
-        await checkVotingComplete();
+        // The server counts the votes; the last voter gets the result
+        showVoteResult(await response.json());
 
     } catch (error) {
         console.error('Error submitting vote:', error);
     }
 }
 
-// Check if all alive players have voted and process results
-async function checkVotingComplete() {
-    try {
-        // Get all alive players
-        const { data: alivePlayers, error: playersError } = await supabase
-            .from('room_players')
-            .select('player_id')
-            .eq('room_id', gameState.roomId)
-            .eq('is_alive', true);
-
-        if (playersError) {
-            console.error('Error getting alive players:', playersError);
-            return;
-        }
+// Show the outcome of a meeting from the server's VoteResult
+function showVoteResult(result) {
+    if (result.status !== 'voting_complete') return;
 
-        // Get all votes for current round
-        const { data: votes, error: votesError } = await supabase
-            .from('votes')
-            .select('voter_id, suspect_id')
-            .eq('room_id', gameState.roomId)
-            .eq('round', gameState.currentRound);
+    const ejected = gameState.players.find(player => player.id === result.ejected_id);
+    const ejectedName = ejected ? ejected.username : 'A player';
 
-        if (votesError) {
-            console.error('Error getting votes:', votesError);
-            return;
-        }
+    if (result.winner === 'crewmates') {
+        showGameOver('crewmates', `${ejectedName} was the Impostor! Crewmates Win!`);
+        return;
+    }
+    if (result.winner) {
+        showGameOver(result.winner, 'The Impostor is no longer outnumbered. Impostor Wins!');
+        return;
+    }
 
-        console.log(`Votes: ${votes.length}/${alivePlayers.length}`);
+    document.getElementById('statusMessage').textContent = result.ejected_id
+        ? `${ejectedName} was ejected. They were a Crewmate.`
+        : 'No one was ejected.';
 
-        // Check if all alive players have voted
-        if (votes.length >= alivePlayers.length) {
-            console.log('All players have voted! Processing results...');
-            await processVotingResults(votes);
-        }
-    } catch (error) {
-        console.error('Error checking voting completion:', error);
-    }
+    // Resume game after delay
+    setTimeout(() => {
+        document.getElementById('votingArea').classList.add('hidden');
+        document.getElementById('statusMessage').textContent = 'Game continues...';
+    }, 3000);
 }
 
-// Process voting results and eliminate player with most votes
-async function processVotingResults(votes) {
-    try {
-        // Count votes for each suspect
-        const voteCount = {};
-        let skipVotes = 0;
+async function sendChat() {
+    const input = document.getElementById('chatInput');
+    const message = input.value.trim();
 
-        votes.forEach(vote => {
-            if (vote.suspect_id) {
-                voteCount[vote.suspect_id] = (voteCount[vote.suspect_id] || 0) + 1;
-            } else {
-                skipVotes++;
-            }
-        });
+    if (!message) return;
 
-        // Find player with most votes
-        let maxVotes = skipVotes;
-        let eliminatedPlayerId = null;
+    try {
+        // Send through the game server so the message is moderated
+        const response = await fetch(`/api/rooms/${gameState.roomCode}/message`, {
+            method: 'POST',
+            headers: playerHeaders(),
+            body: JSON.stringify({
+                player_id: gameState.playerId,
+                content: message
+            })
+        });
 
-        for (const [playerId, count] of Object.entries(voteCount)) {
-            if (count > maxVotes) {
-                maxVotes = count;
-                eliminatedPlayerId = playerId;
-            }
+        if (!response.ok) {
+            // Too long or too fast - keep the text so the player can fix it
+            alert(await errorMessage(response));
+            return;
         }
 
-        // If someone should be eliminated
-        if (eliminatedPlayerId) {
-            console.log('Player to be eliminated:', eliminatedPlayerId);
-
-            // Mark player as not alive
-            const { error: updateError } = await supabase
-                .from('room_players')
-                .update({ is_alive: false })
-                .eq('room_id', gameState.roomId)
-                .eq('player_id', eliminatedPlayerId);
+        const data = await response.json();
+        input.value = '';
+        gameState.seenMessageIds.add(data.message_id);
+        displayMessage({
+            player_id: gameState.playerId,
+            content: data.content,
+            channel: data.channel,
+            username: gameState.playerName,
+            avatar_color: gameState.playerColor,
+            created_at: new Date().toISOString()
+        });
+    } catch (error) {
+        console.error('Error sending message:', error);
+    }
+}
 
-            if (updateError) {
-                console.error('Error eliminating player:', updateError);
-                return;
-            }
+// Quick chat: server-defined phrases for players who find typing slow
+let quickChatPresets = [];
 
-            // Get player info for announcement
-            const { data: eliminatedPlayer } = await supabase
-                .from('players')
-                .select('username')
-                .eq('id', eliminatedPlayerId)
-                .single();
-
-            // Check if eliminated player was the impostor
-            if (eliminatedPlayerId === gameState.impostorId) {
-                showGameOver(true, `${eliminatedPlayer.username} was the Impostor! Crewmates Win!`);
-            } else {
-                // Show elimination result and continue game
-                document.getElementById('statusMessage').textContent =
-                    `${eliminatedPlayer.username} was eliminated. They were a Crewmate.`;
-
-                // Resume game after delay
-                setTimeout(() => {
-                    document.getElementById('votingArea').classList.add('hidden');
-                    document.getElementById('statusMessage').textContent = 'Game continues...';
-                    gameState.currentRound++;
-                }, 3000);
-            }
-        } else {
-            // No one eliminated (skip vote won)
-            document.getElementById('statusMessage').textContent = 'No one was eliminated.';
-            setTimeout(() => {
-                document.getElementById('votingArea').classList.add('hidden');
-                document.getElementById('statusMessage').textContent = 'Game continues...';
-                gameState.currentRound++;
-            }, 3000);
-        }
+async function setupQuickChat() {
+    try {
+        const [catalogResponse, roomResponse] = await Promise.all([
+            fetch('/api/quickchat'),
+            fetch(`/api/rooms/${gameState.roomCode}`)
+        ]);
+        const catalog = await catalogResponse.json();
+        const room = await roomResponse.json();
+
+        quickChatPresets = catalog.presets;
+
+        setOptions(document.getElementById('quickChatPreset'), catalog.presets,
+            preset => preset.id, preset => preset.text);
+        setOptions(document.getElementById('quickChatLocation'), catalog.locations,
+            location => location, location => location);
+        setOptions(document.getElementById('quickChatPlayer'), room.players || [],
+            player => player.id, player => player.username);
+
+        // Some rooms only allow quick chat
+        const presetsOnly = room.settings && room.settings.chat_presets_only;
+        document.getElementById('freeChat').classList.toggle('hidden', presetsOnly);
+
+        updateQuickChatParams();
     } catch (error) {
-        console.error('Error processing voting results:', error);
+        console.error('Error loading quick chat:', error);
     }
 }
 
-async function sendChat() {
-    const input = document.getElementById('chatInput');
-    const message = input.value.trim();
+function updateQuickChatParams() {
+    const presetId = document.getElementById('quickChatPreset').value;
+    const preset = quickChatPresets.find(p => p.id === presetId);
+    const params = preset ? preset.params : [];
 
-    if (!message) return;
+    document.getElementById('quickChatPlayer').classList.toggle('hidden', !params.includes('player'));
+    document.getElementById('quickChatLocation').classList.toggle('hidden', !params.includes('location'));
+}
+
+async function sendQuickChat() {
+    const presetId = document.getElementById('quickChatPreset').value;
+    if (!presetId) return;
 
     try {
-        // Use Supabase API to insert message directly!
-        const { data, error } = await supabase
-            .from('messages')
-            .insert({
-                room_id: gameState.roomId,
+        const response = await fetch(`/api/rooms/${gameState.roomCode}/message`, {
+            method: 'POST',
+            headers: playerHeaders(),
+            body: JSON.stringify({
                 player_id: gameState.playerId,
-                content: message
+                preset_id: presetId,
+                params: {
+                    player: document.getElementById('quickChatPlayer').value,
+                    location: document.getElementById('quickChatLocation').value
+                }
             })
-            .select()
-            .single();
-
-        if (error) throw error;
-
-        input.value = '';
+        });
 
-        // The message will appear via Realtime subscription!
-        console.log('Message sent via Supabase:', data);
+        if (!response.ok) {
+            alert(await errorMessage(response));
+            return;
+        }
 
+        const data = await response.json();
+        gameState.seenMessageIds.add(data.message_id);
+        displayMessage({
+            player_id: gameState.playerId,
+            content: data.content,
+            channel: data.channel,
+            preset_id: data.preset_id,
+            username: gameState.playerName,
+            avatar_color: gameState.playerColor,
+            created_at: new Date().toISOString()
+        });
     } catch (error) {
-        console.error('Error sending message:', error);
-        // Fallback to old API
-        try {
-            const response = await fetch(`/api/rooms/${gameState.roomCode}/message`, {
-                method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({
-                    player_id: gameState.playerId,
-                    content: message
-                })
-            });
-            if (response.ok) {
-                input.value = '';
-                displayMessage({
-                    player_id: gameState.playerId,
-                    content: message,
-                    username: gameState.playerName,
-                    avatar_color: gameState.playerColor,
-                    created_at: new Date().toISOString()
-                });
-            }
-        } catch (fallbackError) {
-            console.error('Fallback also failed:', fallbackError);
-        }
+        console.error('Error sending quick chat:', error);
     }
 }
 
@@ -943,9 +992,21 @@ function displayMessage(message) {
 
     const messageDiv = document.createElement('div');
     messageDiv.className = 'chat-message bg-gray-800 rounded-lg p-2 mb-1';
+    // Ghost and impostor messages are labelled so they aren't mistaken for public chat
+    let channelLabel = '';
+    if (message.channel === 'ghost') {
+        channelLabel = '<span class="text-gray-400">👻</span>';
+    } else if (message.channel === 'impostor') {
+        channelLabel = '<span class="text-red-400">🔪</span>';
+    }
+
+    // Quick-chat phrases are styled the same way for everyone
+    const contentClass = message.preset_id ? 'italic text-purple-200' : '';
+
     messageDiv.innerHTML = `
-        <span class="font-semibold text-${playerColor}-400">${playerName}:</span>
-        <span>${message.content}</span>
+        ${channelLabel}
+        <span class="font-semibold text-${escapeHtml(playerColor)}-400">${escapeHtml(playerName)}:</span>
+        <span class="${contentClass}">${escapeHtml(message.content)}</span>
     `;
 
     chatMessages.appendChild(messageDiv);
@@ -981,6 +1042,7 @@ function resetGame() {
     // Reset game state
     gameState = {
         playerId: null,
+        playerToken: null,
         playerName: '',
         playerColor: 'blue',
         roomId: null,
@@ -988,12 +1050,13 @@ function resetGame() {
         isHost: false,
         role: null,
         isAlive: true,
-        currentRound: 1,
         taskProgress: 0,
         totalTasks: 5,
         players: [],
         subscriptions: null,
-        pollingInterval: null
+        pollingInterval: null,
+        lastMessageId: null,
+        seenMessageIds: new Set()
     };
 
     // Clear inputs
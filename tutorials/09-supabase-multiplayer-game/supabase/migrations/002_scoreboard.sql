@@ -0,0 +1,37 @@
+-- Create games table (one row per game played in a room, including rematches)
+CREATE TABLE games (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    room_id UUID REFERENCES game_rooms(id) ON DELETE CASCADE,
+    impostor_id UUID REFERENCES players(id),
+    winner TEXT, -- crewmates, impostor
+    started_at TIMESTAMP DEFAULT NOW(),
+    ended_at TIMESTAMP
+);
+
+-- Track the game currently being played in each room
+ALTER TABLE game_rooms ADD COLUMN current_game_id UUID REFERENCES games(id) ON DELETE SET NULL;
+
+-- Create room_scores table (running totals across rematches)
+CREATE TABLE room_scores (
+    room_id UUID REFERENCES game_rooms(id) ON DELETE CASCADE,
+    player_id UUID REFERENCES players(id) ON DELETE CASCADE,
+    points INTEGER DEFAULT 0,
+    games_played INTEGER DEFAULT 0,
+    crewmate_wins INTEGER DEFAULT 0,
+    impostor_wins INTEGER DEFAULT 0,
+    correct_votes INTEGER DEFAULT 0,
+    tasks_completed INTEGER DEFAULT 0,
+    PRIMARY KEY (room_id, player_id)
+);
+
+-- Enable Row Level Security
+ALTER TABLE games ENABLE ROW LEVEL SECURITY;
+ALTER TABLE room_scores ENABLE ROW LEVEL SECURITY;
+
+-- Scores are written by the Go server; clients may only read them
+CREATE POLICY "Public games" ON games FOR SELECT USING (true);
+CREATE POLICY "Public room_scores" ON room_scores FOR SELECT USING (true);
+
+-- Enable Realtime
+ALTER PUBLICATION supabase_realtime ADD TABLE games;
+ALTER PUBLICATION supabase_realtime ADD TABLE room_scores;
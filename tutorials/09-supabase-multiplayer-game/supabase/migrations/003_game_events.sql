@@ -0,0 +1,20 @@
+-- Create game_events table (append-only log used for game replays)
+CREATE TABLE game_events (
+    id BIGSERIAL PRIMARY KEY,
+    room_id UUID REFERENCES game_rooms(id) ON DELETE CASCADE,
+    game_id UUID REFERENCES games(id) ON DELETE CASCADE, -- NULL for lobby events
+    event_type TEXT NOT NULL,
+    player_id UUID REFERENCES players(id) ON DELETE SET NULL,
+    target_id UUID REFERENCES players(id) ON DELETE SET NULL,
+    data JSONB,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX game_events_game_id_idx ON game_events (game_id, id);
+CREATE INDEX game_events_room_id_idx ON game_events (room_id, id);
+
+-- Enable Row Level Security
+ALTER TABLE game_events ENABLE ROW LEVEL SECURITY;
+
+-- Events are written by the Go server only. Role assignments are in the
+-- log, so clients read replays through the API instead of directly.
@@ -0,0 +1,24 @@
+-- Create class_sessions table (one row per class period)
+CREATE TABLE class_sessions (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    ended_at TIMESTAMP -- NULL while the session is active
+);
+
+-- Create session_roster table (display names allowed during a session)
+CREATE TABLE session_roster (
+    session_id UUID REFERENCES class_sessions(id) ON DELETE CASCADE,
+    display_name TEXT NOT NULL,
+    normalized_name TEXT NOT NULL, -- lower-cased and trimmed for matching
+    PRIMARY KEY (session_id, normalized_name)
+);
+
+-- Group rooms by the session they were created in
+ALTER TABLE game_rooms ADD COLUMN session_id UUID REFERENCES class_sessions(id) ON DELETE SET NULL;
+
+-- Enable Row Level Security
+ALTER TABLE class_sessions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE session_roster ENABLE ROW LEVEL SECURITY;
+
+-- Sessions and rosters are managed by the teacher through the Go server only
@@ -0,0 +1,16 @@
+-- Create moderation_log table (chat messages the server masked or rejected)
+CREATE TABLE moderation_log (
+    id BIGSERIAL PRIMARY KEY,
+    room_id UUID REFERENCES game_rooms(id) ON DELETE CASCADE,
+    player_id UUID REFERENCES players(id) ON DELETE SET NULL,
+    action TEXT NOT NULL, -- masked, too_long, rate_limited
+    content TEXT NOT NULL, -- the original message
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX moderation_log_room_id_idx ON moderation_log (room_id, created_at);
+
+-- Enable Row Level Security
+ALTER TABLE moderation_log ENABLE ROW LEVEL SECURITY;
+
+-- The log holds unfiltered messages, so only the Go server can read it
@@ -0,0 +1,10 @@
+-- Chat channels: public, ghost (dead players) and impostor (impostor team)
+ALTER TABLE messages ADD COLUMN channel TEXT NOT NULL DEFAULT 'public';
+
+-- Meeting state (NULL outside of emergency meetings) and room chat settings
+ALTER TABLE game_rooms ADD COLUMN meeting_started_at TIMESTAMP;
+ALTER TABLE game_rooms ADD COLUMN impostor_chat BOOLEAN DEFAULT false;
+
+-- Ghost and impostor messages must not leak through the public API
+DROP POLICY "Public messages" ON messages;
+CREATE POLICY "Public messages" ON messages FOR SELECT USING (channel = 'public');
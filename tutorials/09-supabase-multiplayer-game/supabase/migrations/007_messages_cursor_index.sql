@@ -0,0 +1,2 @@
+-- Support cursor pagination of chat messages (?after=<id>&limit=)
+CREATE INDEX messages_room_cursor_idx ON messages (room_id, created_at, id);
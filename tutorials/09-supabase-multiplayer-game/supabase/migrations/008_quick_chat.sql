@@ -0,0 +1,5 @@
+-- Quick-chat messages remember which preset they were rendered from
+ALTER TABLE messages ADD COLUMN preset_id TEXT;
+
+-- Room setting: only allow quick-chat presets (no free text)
+ALTER TABLE game_rooms ADD COLUMN chat_presets_only BOOLEAN DEFAULT false;
@@ -0,0 +1,25 @@
+-- Tie votes to their game so each player gets one vote per round
+ALTER TABLE votes ADD COLUMN game_id UUID REFERENCES games(id) ON DELETE CASCADE;
+
+-- Older votes belong to the game that was running in their room
+UPDATE votes v
+SET game_id = g.id
+FROM games g
+WHERE v.room_id = g.room_id
+  AND v.created_at >= g.started_at
+  AND (g.ended_at IS NULL OR v.created_at <= g.ended_at);
+
+-- Keep only the first vote of anyone who voted more than once
+DELETE FROM votes a
+USING votes b
+WHERE a.game_id = b.game_id
+  AND a.round = b.round
+  AND a.voter_id = b.voter_id
+  AND (a.created_at, a.id) > (b.created_at, b.id);
+
+CREATE UNIQUE INDEX votes_one_per_round_idx ON votes (game_id, round, voter_id);
+
+-- Votes are cast through the Go server, which checks the voter; clients
+-- can still read them
+DROP POLICY "Public votes" ON votes;
+CREATE POLICY "Public votes read" ON votes FOR SELECT USING (true);
@@ -0,0 +1,11 @@
+-- Secret tokens issued when a player joins. Player IDs are public (every
+-- client sees the room's players), so private chat channels are only
+-- shown to requests that also carry the player's token.
+CREATE TABLE player_tokens (
+    player_id UUID PRIMARY KEY REFERENCES players(id) ON DELETE CASCADE,
+    token TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Read and written by the Go server only: no policies, so clients can't
+ALTER TABLE player_tokens ENABLE ROW LEVEL SECURITY;
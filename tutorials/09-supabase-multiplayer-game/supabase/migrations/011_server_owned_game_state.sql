@@ -0,0 +1,24 @@
+-- Each task a player finishes, once per game, so repeats don't count twice
+CREATE TABLE task_completions (
+    game_id UUID REFERENCES games(id) ON DELETE CASCADE,
+    player_id UUID REFERENCES players(id) ON DELETE CASCADE,
+    task_id TEXT NOT NULL,
+    completed_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (game_id, player_id, task_id)
+);
+
+-- The meeting currently open, or last held, in each game. Votes are
+-- counted for this round whatever the client says.
+ALTER TABLE games ADD COLUMN meeting_round INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE task_completions ENABLE ROW LEVEL SECURITY;
+CREATE POLICY "Public task_completions read" ON task_completions FOR SELECT USING (true);
+
+-- Players, rooms, task counts and who is alive are written by the Go
+-- server only. Clients keep reading them for the lobby and progress bar.
+DROP POLICY "Public players" ON players;
+DROP POLICY "Public game_rooms" ON game_rooms;
+DROP POLICY "Public room_players" ON room_players;
+CREATE POLICY "Public players read" ON players FOR SELECT USING (true);
+CREATE POLICY "Public game_rooms read" ON game_rooms FOR SELECT USING (true);
+CREATE POLICY "Public room_players read" ON room_players FOR SELECT USING (true);
@@ -0,0 +1,6 @@
+-- Joining goes through the Go server, which checks the class roster.
+-- 011 left players and room_players read-only under RLS; revoking the
+-- write grants as well keeps the roster enforced even if a policy is
+-- added back by hand.
+REVOKE INSERT, UPDATE, DELETE ON players FROM anon, authenticated;
+REVOKE INSERT, UPDATE, DELETE ON room_players FROM anon, authenticated;
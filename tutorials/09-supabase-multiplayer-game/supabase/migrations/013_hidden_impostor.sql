@@ -0,0 +1,10 @@
+-- Only the server knows who the impostor is until the game ends. It tells
+-- the impostor through GET /api/rooms/{code}; clients can still read every
+-- other column of game_rooms and games, but not impostor_id.
+REVOKE SELECT ON game_rooms, games FROM anon, authenticated;
+GRANT SELECT (id, room_code, host_id, status, created_at, current_game_id, session_id,
+    meeting_started_at, impostor_chat, chat_presets_only) ON game_rooms TO anon, authenticated;
+GRANT SELECT (id, room_id, winner, started_at, ended_at, meeting_round) ON games TO anon, authenticated;
+
+-- Realtime sends whole rows, so these tables are polled instead
+ALTER PUBLICATION supabase_realtime DROP TABLE game_rooms, games;
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"crewmate-crisis/api"
+)
+
+func (s *Server) getTranscript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	roomCode := vars["code"]
+
+	// Default to the most recently finished game in the room
+	gameID := r.URL.Query().Get("game_id")
+	if gameID != "" && !isUUID(gameID) {
+		httpError(w, r, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+	if gameID == "" {
+		err := s.db.QueryRowContext(ctx, `
+			SELECT g.id FROM games g
+			JOIN game_rooms gr ON g.room_id = gr.id
+			WHERE gr.room_code = $1 AND g.ended_at IS NOT NULL
+			ORDER BY g.ended_at DESC
+			LIMIT 1`,
+			roomCode).Scan(&gameID)
+
+		if err == sql.ErrNoRows {
+			httpError(w, r, "No finished games in this room", http.StatusNotFound)
+			return
+		} else if err != nil {
+			serverError(w, r, "Failed to find game", err)
+			return
+		}
+	}
+
+	transcript, err := s.buildTranscript(ctx, roomCode, gameID)
+	if err == sql.ErrNoRows {
+		httpError(w, r, "Finished game not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		serverError(w, r, "Failed to build transcript", err)
+		return
+	}
+
+	filename := fmt.Sprintf("crewmate-%s-%s", roomCode, transcript.StartedAt.Format("2006-01-02-1504"))
+
+	switch r.URL.Query().Get("format") {
+	case "markdown", "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".md"))
+		w.Write([]byte(transcriptMarkdown(transcript)))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		json.NewEncoder(w).Encode(transcript)
+	}
+}
+
+// buildTranscript collects a finished game's messages, votes and events
+func (s *Server) buildTranscript(ctx context.Context, roomCode, gameID string) (*api.Transcript, error) {
+	t := &api.Transcript{RoomCode: roomCode, GameID: gameID}
+
+	var roomID, impostorID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT g.room_id, g.impostor_id, g.winner, g.started_at, g.ended_at
+		FROM games g
+		JOIN game_rooms gr ON g.room_id = gr.id
+		WHERE g.id = $1 AND gr.room_code = $2 AND g.ended_at IS NOT NULL`,
+		gameID, roomCode).Scan(&roomID, &impostorID, &t.Winner, &t.StartedAt, &t.EndedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Players and their roles, from the roles dealt when the game started.
+	// The room's players may have changed since.
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.id, p.username
+		FROM game_events e
+		JOIN players p ON e.player_id = p.id
+		WHERE e.game_id = $1 AND e.event_type = $2
+		ORDER BY p.username`,
+		gameID, eventRoleAssigned)
+
+	if err != nil {
+		return nil, err
+	}
+
+	usernames := make(map[string]string)
+	for rows.Next() {
+		var player api.TranscriptPlayer
+		if err := rows.Scan(&player.ID, &player.Username); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		player.Role = "crewmate"
+		if player.ID == impostorID {
+			player.Role = "impostor"
+		}
+		usernames[player.ID] = player.Username
+		t.Players = append(t.Players, player)
+	}
+	rows.Close()
+
+	// Chat messages sent while the game was running
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT m.player_id, p.username, m.content, m.channel, m.created_at
+		FROM messages m
+		JOIN players p ON m.player_id = p.id
+		WHERE m.room_id = $1 AND m.created_at BETWEEN $2 AND $3
+		ORDER BY m.created_at ASC`,
+		roomID, t.StartedAt, t.EndedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		entry := api.TranscriptEntry{Kind: "chat"}
+		if err := rows.Scan(&entry.PlayerID, &entry.Username, &entry.Content, &entry.Channel, &entry.Time); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t.Timeline = append(t.Timeline, entry)
+	}
+	rows.Close()
+
+	// Meetings and ejections from the game event log
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT event_type, player_id, target_id, data, created_at
+		FROM game_events
+		WHERE game_id = $1 AND event_type IN ($2, $3)
+		ORDER BY id ASC`,
+		gameID, eventMeetingCalled, eventPlayerEjected)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ejections := make(map[int]string)
+	for rows.Next() {
+		var eventType string
+		var playerID, targetID sql.NullString
+		var data []byte
+		var createdAt time.Time
+		if err := rows.Scan(&eventType, &playerID, &targetID, &data, &createdAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		switch eventType {
+		case eventMeetingCalled:
+			t.Timeline = append(t.Timeline, api.TranscriptEntry{
+				Time:     createdAt,
+				Kind:     "meeting",
+				PlayerID: playerID.String,
+				Username: usernames[playerID.String],
+			})
+		case eventPlayerEjected:
+			var details struct {
+				Round int `json:"round"`
+			}
+			json.Unmarshal(data, &details)
+			ejections[details.Round] = targetID.String
+		}
+	}
+	rows.Close()
+
+	sort.SliceStable(t.Timeline, func(i, j int) bool {
+		return t.Timeline[i].Time.Before(t.Timeline[j].Time)
+	})
+
+	// Votes grouped by round
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT round, voter_id, suspect_id
+		FROM votes
+		WHERE game_id = $1
+		ORDER BY round ASC, created_at ASC`,
+		gameID)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var round int
+		var voterID string
+		var suspectID sql.NullString
+		if err := rows.Scan(&round, &voterID, &suspectID); err != nil {
+			return nil, err
+		}
+
+		if len(t.Rounds) == 0 || t.Rounds[len(t.Rounds)-1].Round != round {
+			vr := api.VoteRound{Round: round, Votes: []api.Vote{}}
+			if ejectedID, ok := ejections[round]; ok {
+				vr.EjectedID = ejectedID
+				vr.Ejected = usernames[ejectedID]
+				vr.WasImpostor = ejectedID == impostorID
+			}
+			t.Rounds = append(t.Rounds, vr)
+		}
+
+		current := &t.Rounds[len(t.Rounds)-1]
+		current.Votes = append(current.Votes, api.Vote{
+			Voter:   usernames[voterID],
+			Suspect: usernames[suspectID.String],
+		})
+	}
+
+	return t, rows.Err()
+}
+
+// transcriptMarkdown renders the transcript as a document for class discussion
+func transcriptMarkdown(t *api.Transcript) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Crewmate Crisis – Room %s\n\n", t.RoomCode)
+	fmt.Fprintf(&b, "- **Played:** %s – %s\n", t.StartedAt.Format("Jan 2, 2006 3:04 PM"), t.EndedAt.Format("3:04 PM"))
+	switch t.Winner {
+	case winnerImpostor:
+		b.WriteString("- **Result:** The impostor won\n\n")
+	case winnerCrewmates:
+		b.WriteString("- **Result:** The crewmates won\n\n")
+	case winnerNobody:
+		b.WriteString("- **Result:** The game was ended early\n\n")
+	}
+
+	b.WriteString("## Players\n\n")
+	for _, player := range t.Players {
+		fmt.Fprintf(&b, "- %s (%s)\n", player.Username, player.Role)
+	}
+
+	b.WriteString("\n## Discussion\n\n")
+	if len(t.Timeline) == 0 {
+		b.WriteString("_No chat messages._\n")
+	}
+	for _, entry := range t.Timeline {
+		switch entry.Kind {
+		case "meeting":
+			caller := entry.Username
+			if caller == "" {
+				caller = "Someone"
+			}
+			fmt.Fprintf(&b, "\n**🚨 %s called an emergency meeting** (%s)\n\n", caller, entry.Time.Format("3:04:05 PM"))
+		default:
+			channel := ""
+			if entry.Channel != channelPublic {
+				channel = fmt.Sprintf(" _(%s chat)_", entry.Channel)
+			}
+			fmt.Fprintf(&b, "- `%s` **%s:**%s %s\n", entry.Time.Format("3:04:05"), entry.Username, channel, entry.Content)
+		}
+	}
+
+	b.WriteString("\n## Votes\n")
+	if len(t.Rounds) == 0 {
+		b.WriteString("\n_No votes were cast._\n")
+	}
+	for _, round := range t.Rounds {
+		fmt.Fprintf(&b, "\n### Round %d\n\n", round.Round)
+		for _, vote := range round.Votes {
+			if vote.Suspect == "" {
+				fmt.Fprintf(&b, "- %s skipped\n", vote.Voter)
+			} else {
+				fmt.Fprintf(&b, "- %s accused %s\n", vote.Voter, vote.Suspect)
+			}
+		}
+
+		switch {
+		case round.Ejected == "":
+			b.WriteString("\n**Outcome:** Nobody was ejected.\n")
+		case round.WasImpostor:
+			fmt.Fprintf(&b, "\n**Outcome:** %s was ejected. They were the impostor!\n", round.Ejected)
+		default:
+			fmt.Fprintf(&b, "\n**Outcome:** %s was ejected. They were a crewmate.\n", round.Ejected)
+		}
+	}
+
+	b.WriteString("\n## Discussion Questions\n\n")
+	b.WriteString("1. What evidence pointed to the impostor? When did it first appear in the chat?\n")
+	b.WriteString("2. Which accusations were based on evidence and which were guesses?\n")
+	b.WriteString("3. How did the votes change from round to round?\n")
+
+	return b.String()
+}
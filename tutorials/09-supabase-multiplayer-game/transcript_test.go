@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"crewmate-crisis/api"
+)
+
+func TestTranscriptMarkdown(t *testing.T) {
+	start := time.Date(2025, 3, 14, 13, 5, 0, 0, time.UTC)
+	transcript := &api.Transcript{
+		RoomCode:  "ABCDEF",
+		Winner:    winnerCrewmates,
+		StartedAt: start,
+		EndedAt:   start.Add(12 * time.Minute),
+		Players: []api.TranscriptPlayer{
+			{ID: "p1", Username: "ada", Role: "Crewmate"},
+			{ID: "p2", Username: "grace", Role: "Impostor"},
+			{ID: "p3", Username: "linus", Role: "Crewmate"},
+		},
+		Timeline: []api.TranscriptEntry{
+			{Time: start.Add(2 * time.Minute), Kind: "meeting", Username: "ada"},
+			{Time: start.Add(2*time.Minute + 10*time.Second), Kind: "chat", Channel: channelPublic, Username: "ada", Content: "grace was in Electrical"},
+			{Time: start.Add(3 * time.Minute), Kind: "chat", Channel: channelGhost, Username: "linus", Content: "it was grace"},
+			{Time: start.Add(4 * time.Minute), Kind: "meeting"},
+		},
+		Rounds: []api.VoteRound{
+			{Round: 1, Votes: []api.Vote{{Voter: "ada", Suspect: "linus"}, {Voter: "grace"}}, Ejected: "linus"},
+			{Round: 2, Votes: []api.Vote{{Voter: "ada", Suspect: "grace"}}, Ejected: "grace", WasImpostor: true},
+			{Round: 3},
+		},
+	}
+
+	want := `# Crewmate Crisis – Room ABCDEF
+
+- **Played:** Mar 14, 2025 1:05 PM – 1:17 PM
+- **Result:** The crewmates won
+
+## Players
+
+- ada (Crewmate)
+- grace (Impostor)
+- linus (Crewmate)
+
+## Discussion
+
+
+**🚨 ada called an emergency meeting** (1:07:00 PM)
+
+- ` + "`1:07:10`" + ` **ada:** grace was in Electrical
+- ` + "`1:08:00`" + ` **linus:** _(ghost chat)_ it was grace
+
+**🚨 Someone called an emergency meeting** (1:09:00 PM)
+
+
+## Votes
+
+### Round 1
+
+- ada accused linus
+- grace skipped
+
+**Outcome:** linus was ejected. They were a crewmate.
+
+### Round 2
+
+- ada accused grace
+
+**Outcome:** grace was ejected. They were the impostor!
+
+### Round 3
+
+
+**Outcome:** Nobody was ejected.
+
+## Discussion Questions
+
+1. What evidence pointed to the impostor? When did it first appear in the chat?
+2. Which accusations were based on evidence and which were guesses?
+3. How did the votes change from round to round?
+`
+	if got := transcriptMarkdown(transcript); got != want {
+		t.Errorf("transcriptMarkdown =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTranscriptMarkdownResult(t *testing.T) {
+	tests := []struct {
+		winner string
+		want   string
+	}{
+		{winnerCrewmates, "- **Result:** The crewmates won\n"},
+		{winnerImpostor, "- **Result:** The impostor won\n"},
+		{winnerNobody, "- **Result:** The game was ended early\n"},
+	}
+	for _, tt := range tests {
+		got := transcriptMarkdown(&api.Transcript{RoomCode: "ABCDEF", Winner: tt.winner})
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("winner %q: transcript has no %q:\n%s", tt.winner, tt.want, got)
+		}
+		// A game with no chat or votes still reads as a complete document
+		for _, empty := range []string{"_No chat messages._", "_No votes were cast._"} {
+			if !strings.Contains(got, empty) {
+				t.Errorf("winner %q: transcript has no %q", tt.winner, empty)
+			}
+		}
+	}
+}